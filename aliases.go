@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	// Newest tagged release in the index.
+	AliasStable = "stable"
+	// Newest downloaded version.
+	AliasLatest = "latest"
+)
+
+// Registry of user defined version aliases, e.g. `work` for `0.12.0`.
+type AliasRegistry struct {
+	// Maps an alias to a version, `master`, a built-in alias or the name of a
+	// custom toolchain.
+	Aliases map[string]string `json:"aliases"`
+}
+
+func aliasRegistryPath() string {
+	return localDirPath("aliases.json")
+}
+
+func LoadAliasRegistry() (*AliasRegistry, error) {
+	registry := &AliasRegistry{Aliases: map[string]string{}}
+
+	data, err := os.ReadFile(aliasRegistryPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return registry, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, registry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", aliasRegistryPath(), err)
+	}
+	if registry.Aliases == nil {
+		registry.Aliases = map[string]string{}
+	}
+
+	return registry, nil
+}
+
+func (r *AliasRegistry) Save() error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(aliasRegistryPath(), data, 0644)
+}
+
+// Returns the user defined aliases, loaded on first use.
+func (app *AppState) userAliases() map[string]string {
+	if app.aliases == nil {
+		registry, err := LoadAliasRegistry()
+		if err != nil {
+			app.fail(err)
+		}
+		app.aliases = registry.Aliases
+	}
+
+	return app.aliases
+}
+
+// Returns the newest downloaded version.
+func (app *AppState) latestDownloadedItem() (*Item, bool) {
+	var latest *Item
+	for i := 0; i < len(app.Items); i++ {
+		item := &app.Items[i]
+		if !item.Downloaded || item.Custom {
+			continue
+		}
+		if latest == nil || item.Version.moreThan(latest.Version) {
+			latest = item
+		}
+	}
+
+	return latest, latest != nil
+}
+
+// Returns the item a name that isn't a full version refers to: a custom
+// toolchain, a user defined alias, one of the built-in aliases, a channel, a
+// partial version or a version range.
+func (app *AppState) itemForName(name string) (*Item, bool) {
+	if item, ok := app.GetCustomToolchain(name); ok {
+		return item, true
+	}
+
+	if target, ok := app.userAliases()[name]; ok {
+		return app.itemForPin(target)
+	}
+
+	if name == AliasLatest {
+		return app.latestDownloadedItem()
+	}
+	if isChannel(name) {
+		return app.channelItem(name)
+	}
+	if isNomination(name) {
+		return app.itemForNomination(name)
+	}
+
+	if item, ok := app.itemForPartialVersion(name); ok {
+		return item, true
+	}
+
+	return app.itemForRange(name)
+}
+
+// Returns the user defined aliases pointing at item.
+func (app *AppState) aliasesFor(item *Item) []string {
+	result := []string{}
+	for name, target := range app.userAliases() {
+		aliased, ok := app.itemForPin(target)
+		if ok && aliased.Custom == item.Custom && aliased.Name() == item.Name() && aliased.Version.equal(item.Version) {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+func isReservedName(name string) bool {
+	return isChannel(name) || name == AliasLatest
+}
+
+func (app *AppState) commandAlias(name string, target string) {
+	if isReservedName(name) {
+		logger.errorf("%s is a built-in alias!\n", name)
+		os.Exit(1)
+	}
+	if _, partial := parsePartialVersion(name); partial {
+		logger.errorf("Aliases can't be version numbers!\n")
+		os.Exit(1)
+	}
+	if _, err := ParseVersion(name); err == nil {
+		logger.errorf("Aliases can't be version numbers!\n")
+		os.Exit(1)
+	}
+	if _, ok := app.GetCustomToolchain(name); ok {
+		logger.errorf("%s is already the name of a custom toolchain!\n", name)
+		os.Exit(1)
+	}
+
+	// Aliases can't point at each other, so resolving them never loops.
+	if _, ok := app.userAliases()[target]; ok {
+		logger.errorf("%s is an alias itself!\n", target)
+		os.Exit(1)
+	}
+	if _, ok := app.itemForPin(target); !ok {
+		app.fail(&VersionNotFoundError{Name: target})
+	}
+
+	registry, err := LoadAliasRegistry()
+	if err != nil {
+		app.fail(err)
+	}
+	registry.Aliases[name] = target
+	if err = registry.Save(); err != nil {
+		app.fail(err)
+	}
+
+	logger.infof("%s -> %s\n", name, target)
+}
+
+func (app *AppState) commandUnalias(name string) {
+	registry, err := LoadAliasRegistry()
+	if err != nil {
+		app.fail(err)
+	}
+
+	if _, ok := registry.Aliases[name]; !ok {
+		logger.errorf("Alias not found!\n")
+		os.Exit(1)
+	}
+
+	delete(registry.Aliases, name)
+	if err = registry.Save(); err != nil {
+		app.fail(err)
+	}
+
+	logger.infof("Removed alias %s\n", name)
+}
+
+func (app *AppState) commandListAliases() {
+	aliases := app.userAliases()
+	names := []string{}
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		resolved := "not found"
+		if item, ok := app.itemForPin(aliases[name]); ok {
+			resolved = item.Name()
+		}
+		fmt.Printf("%s -> %s (%s)\n", name, aliases[name], resolved)
+	}
+
+	for _, name := range []string{AliasStable, AliasLatest} {
+		if item, ok := app.itemForName(name); ok {
+			fmt.Printf("%s -> %s (built-in)\n", name, item.Version.String())
+		}
+	}
+}
+
+// Formats the aliases of item for list output, e.g. ` [work, ci]`.
+func (app *AppState) aliasDescription(item *Item) string {
+	aliases := app.aliasesFor(item)
+	if len(aliases) == 0 {
+		return ""
+	}
+
+	return " [" + strings.Join(aliases, ", ") + "]"
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ulikunitz/xz"
+)
+
+// Called with the number of archive bytes extracted so far and the size of
+// the archive.
+type extractProgressFunc func(read int64, total int64)
+
+// Opens the tar stream of the .tar.xz archive at tarballPath. The returned
+// file must be closed by the caller.
+func openTarball(tarballPath string, onProgress extractProgressFunc) (*tar.Reader, *os.File, error) {
+	file, err := os.Open(tarballPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var reader io.Reader = file
+	if onProgress != nil {
+		total := int64(0)
+		if info, err := file.Stat(); err == nil {
+			total = info.Size()
+		}
+		reader = &progressReader{reader: file, onProgress: func(read int64) { onProgress(read, total) }}
+	}
+
+	xzReader, err := xz.NewReader(bufio.NewReader(reader))
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("%s: %w", tarballPath, err)
+	}
+
+	return tar.NewReader(xzReader), file, nil
+}
+
+// Checks that the archive member name stays inside the destination.
+// Archives can come from mirrors, so members with absolute paths or `..`
+// components are rejected.
+func checkMemberName(name string) error {
+	if path.IsAbs(name) || filepath.IsAbs(name) || strings.HasPrefix(name, "\\") {
+		return fmt.Errorf("absolute path %s in archive", name)
+	}
+
+	for _, part := range memberParts(name) {
+		if part == ".." {
+			return fmt.Errorf("path %s escapes the destination", name)
+		}
+	}
+
+	return nil
+}
+
+func memberParts(name string) []string {
+	return strings.FieldsFunc(name, func(r rune) bool { return r == '/' || r == '\\' })
+}
+
+// Returns the path inside dir of the archive member name.
+func memberPath(dir string, name string) (string, error) {
+	if err := checkMemberName(name); err != nil {
+		return "", err
+	}
+
+	// Members must not be written through symlinks extracted before them,
+	// which could point anywhere once combined, nor replace one, since
+	// opening it would follow it.
+	p := dir
+	for _, part := range memberParts(name) {
+		p = filepath.Join(p, part)
+		if info, err := os.Lstat(p); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("path %s goes through a symlink", name)
+		}
+	}
+
+	return filepath.Join(dir, filepath.FromSlash(name)), nil
+}
+
+// Checks that the symlink member name pointing at linkname stays inside the
+// archive extracted to dir, following the symlinks extracted so far.
+func checkSymlinkTarget(dir string, name string, linkname string) error {
+	if path.IsAbs(linkname) || filepath.IsAbs(linkname) {
+		return fmt.Errorf("symlink %s points to absolute path %s", name, linkname)
+	}
+
+	if !resolvesInside(dir, path.Dir(filepath.ToSlash(name))+"/"+linkname) {
+		return fmt.Errorf("symlink %s points outside of the archive", name)
+	}
+
+	return nil
+}
+
+// Reports whether the relative path p stays inside dir, resolving it one
+// component at a time and following the symlinks already in dir, rather
+// than cleaning it lexically.
+func resolvesInside(dir string, p string) bool {
+	parts := memberParts(p)
+	resolved := []string{}
+	for links := 0; len(parts) > 0; {
+		part := parts[0]
+		parts = parts[1:]
+
+		switch part {
+		case ".":
+			continue
+		case "..":
+			if len(resolved) == 0 {
+				return false
+			}
+			resolved = resolved[:len(resolved)-1]
+			continue
+		}
+
+		resolved = append(resolved, part)
+		link, err := os.Readlink(filepath.Join(dir, filepath.Join(resolved...)))
+		if err != nil {
+			// Not a symlink, or not extracted yet.
+			continue
+		}
+
+		links++
+		if links > 40 || path.IsAbs(link) || filepath.IsAbs(link) {
+			return false
+		}
+		resolved = resolved[:len(resolved)-1]
+		parts = append(memberParts(link), parts...)
+	}
+
+	return true
+}
+
+// Checks that every symlink extracted into dir stays inside it, which
+// symlinks extracted after them may have changed.
+func checkExtractedSymlinks(dir string) error {
+	return filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.Type()&os.ModeSymlink == 0 {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		link, err := os.Readlink(p)
+		if err != nil {
+			return err
+		}
+
+		return checkSymlinkTarget(dir, filepath.ToSlash(rel), link)
+	})
+}
+
+// Lists the paths of the entries in the tarball at tarballPath.
+func tarballEntries(tarballPath string) ([]string, error) {
+	if archiveExt(tarballPath) == ".zip" {
+		return zipEntries(tarballPath)
+	}
+
+	reader, file, err := openTarball(tarballPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries := []string{}
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return entries, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %w", tarballPath, err)
+		}
+		entries = append(entries, header.Name)
+	}
+}
+
+// Returns the name of the top-level directory of the archive at
+// tarballPath, only reading as far as its first member.
+func archiveTopLevelDir(tarballPath string) (string, error) {
+	var name string
+	if archiveExt(tarballPath) == ".zip" {
+		entries, err := zipEntries(tarballPath)
+		if err != nil {
+			return "", err
+		}
+		if len(entries) > 0 {
+			name = entries[0]
+		}
+	} else {
+		reader, file, err := openTarball(tarballPath, nil)
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+
+		header, err := reader.Next()
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", tarballPath, err)
+		}
+		name = header.Name
+	}
+
+	name = strings.TrimPrefix(name, "./")
+	if i := strings.Index(name, "/"); i > 0 {
+		return name[:i], nil
+	}
+	if name == "" {
+		return "", fmt.Errorf("%s is empty", tarballPath)
+	}
+
+	return name, nil
+}
+
+// Extracts the tarball (or, on Windows, zip archive) at tarballPath into dir.
+func extractTarball(tarballPath string, dir string) error {
+	return extractTarballWithProgress(tarballPath, dir, nil)
+}
+
+// Same as extractTarball, reporting progress to onProgress if not nil.
+func extractTarballWithProgress(tarballPath string, dir string, onProgress extractProgressFunc) error {
+	logger.debugf("Extracting %s to %s\n", tarballPath, dir)
+	if archiveExt(tarballPath) == ".zip" {
+		return extractZip(tarballPath, dir, onProgress)
+	}
+
+	reader, file, err := openTarball(tarballPath, onProgress)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err = extractTar(reader, dir); err != nil {
+		return fmt.Errorf("%s: %w", tarballPath, err)
+	}
+
+	return nil
+}
+
+// Extracts the .tar.xz stream read from r into dir.
+func extractTarXzStream(r io.Reader, dir string) error {
+	xzReader, err := xz.NewReader(bufio.NewReader(r))
+	if err != nil {
+		return err
+	}
+
+	return extractTar(tar.NewReader(xzReader), dir)
+}
+
+func extractTar(reader *tar.Reader, dir string) error {
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return checkExtractedSymlinks(dir)
+		} else if err != nil {
+			return err
+		}
+
+		logger.tracef("%s\n", header.Name)
+		if err = extractEntry(reader, header, dir); err != nil {
+			return fmt.Errorf("extracting %s: %w", header.Name, err)
+		}
+	}
+}
+
+func extractEntry(reader io.Reader, header *tar.Header, dir string) error {
+	target, err := memberPath(dir, header.Name)
+	if err != nil {
+		return err
+	}
+	mode := header.FileInfo().Mode()
+
+	switch header.Typeflag {
+	case tar.TypeDir:
+		return os.MkdirAll(target, mode.Perm()|0700)
+
+	case tar.TypeReg:
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode.Perm())
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(file, reader)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+
+	case tar.TypeSymlink:
+		if err := checkSymlinkTarget(dir, header.Name, header.Linkname); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return err
+		}
+		return os.Symlink(header.Linkname, target)
+
+	case tar.TypeLink:
+		source, err := memberPath(dir, header.Linkname)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return err
+		}
+		return os.Link(source, target)
+	}
+
+	// Other entries, like the pax global header, carry no files.
+	return nil
+}
+
+func zipEntries(zipPath string) ([]string, error) {
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", zipPath, err)
+	}
+	defer reader.Close()
+
+	entries := []string{}
+	for _, f := range reader.File {
+		entries = append(entries, f.Name)
+	}
+
+	return entries, nil
+}
+
+// Extracts the zip archive at zipPath into dir.
+func extractZip(zipPath string, dir string, onProgress extractProgressFunc) error {
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return fmt.Errorf("%s: %w", zipPath, err)
+	}
+	defer reader.Close()
+
+	total := int64(0)
+	for _, f := range reader.File {
+		total += int64(f.CompressedSize64)
+	}
+
+	read := int64(0)
+	last := time.Time{}
+	for i, f := range reader.File {
+		logger.tracef("%s\n", f.Name)
+		if err = extractZipEntry(f, dir); err != nil {
+			return fmt.Errorf("%s: extracting %s: %w", zipPath, f.Name, err)
+		}
+
+		read += int64(f.CompressedSize64)
+		if onProgress != nil && (i == len(reader.File)-1 || time.Since(last) >= progressInterval) {
+			last = time.Now()
+			onProgress(read, total)
+		}
+	}
+
+	return nil
+}
+
+// Makes sure the binaries of the toolchain extracted at dir are executable,
+// since some filesystems lose the permissions from the archive.
+func fixToolchainPermissions(dir string) error {
+	if getHostOs() == "windows" {
+		return nil
+	}
+
+	for _, name := range []string{"zig", "zls"} {
+		p := filepath.Join(dir, name)
+		info, err := os.Stat(p)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if info.Mode()&0111 != 0111 {
+			if err = os.Chmod(p, info.Mode().Perm()|0111); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func extractZipEntry(f *zip.File, dir string) error {
+	target, err := memberPath(dir, f.Name)
+	if err != nil {
+		return err
+	}
+
+	if f.FileInfo().IsDir() {
+		return os.MkdirAll(target, os.ModePerm)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+
+	in, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, f.Mode().Perm()|0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,87 @@
+package main
+
+import "strings"
+
+// Command line arguments split into positional arguments and `--flag`,
+// `--flag=value` or `--flag value` options. Everything after a bare `--` is
+// kept verbatim in Rest.
+type Args struct {
+	Positional []string
+	Flags      map[string]string
+	Rest       []string
+}
+
+// Parses args. Flags listed in valueFlags (and global ones) take a value,
+// either inline (`--flag=value`) or as the next argument; all other flags are
+// booleans. `-h` is short for `--help`.
+func ParseArgs(args []string, valueFlags ...string) *Args {
+	result := &Args{
+		Positional: []string{},
+		Flags:      map[string]string{},
+		Rest:       []string{},
+	}
+
+	for _, flag := range globalFlags {
+		if flag.Value != "" {
+			valueFlags = append(valueFlags, flag.Name)
+		}
+	}
+	takesValue := func(name string) bool {
+		for _, f := range valueFlags {
+			if f == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if arg == "--" {
+			result.Rest = append(result.Rest, args[i+1:]...)
+			break
+		}
+
+		if arg == "-h" {
+			result.Flags["help"] = ""
+			continue
+		} else if arg == "-q" {
+			result.Flags["quiet"] = ""
+			continue
+		} else if arg == "-v" {
+			result.Flags["verbose"] = ""
+			continue
+		} else if arg == "-vv" {
+			result.Flags["verbose"] = "2"
+			continue
+		}
+
+		if !strings.HasPrefix(arg, "--") {
+			result.Positional = append(result.Positional, arg)
+			continue
+		}
+
+		name := strings.TrimPrefix(arg, "--")
+		if sp := strings.SplitN(name, "=", 2); len(sp) == 2 {
+			result.Flags[sp[0]] = sp[1]
+		} else if takesValue(name) && i+1 < len(args) {
+			result.Flags[name] = args[i+1]
+			i++
+		} else {
+			result.Flags[name] = ""
+		}
+	}
+
+	return result
+}
+
+func (a *Args) Has(name string) bool {
+	_, ok := a.Flags[name]
+	return ok
+}
+
+func (a *Args) Value(name string) (string, bool) {
+	v, ok := a.Flags[name]
+	return v, ok && v != ""
+}
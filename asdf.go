@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Scripts of the asdf plugin protocol, all of which delegate to the
+// `zig-toolchain asdf` subcommands. mise understands asdf plugins too.
+var asdfPluginScripts = map[string]string{
+	"list-all":       "exec zig-toolchain asdf list-all\n",
+	"latest-stable":  "exec zig-toolchain asdf latest-stable\n",
+	"install":        "exec zig-toolchain asdf install\n",
+	"list-bin-paths": "exec zig-toolchain asdf list-bin-paths\n",
+	"exec-env":       "eval \"$(zig-toolchain asdf exec-env)\"\n",
+}
+
+// Returns the indexed items sorted from oldest to newest, as asdf expects.
+func (app *AppState) asdfVersions() []*Item {
+	result := []*Item{}
+	for i := 0; i < len(app.Items); i++ {
+		if app.Items[i].Indexed {
+			result = append(result, &app.Items[i])
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version.lessThan(result[j].Version)
+	})
+
+	return result
+}
+
+func (app *AppState) commandAsdfListAll() {
+	versions := []string{}
+	for _, item := range app.asdfVersions() {
+		versions = append(versions, item.Version.FullString())
+	}
+
+	fmt.Printf("%s\n", strings.Join(versions, " "))
+}
+
+func (app *AppState) commandAsdfLatestStable() {
+	versions := app.asdfVersions()
+	for i := len(versions) - 1; i >= 0; i-- {
+		if !versions[i].Version.isPrerelease() {
+			fmt.Printf("%s\n", versions[i].Version.FullString())
+			return
+		}
+	}
+
+	os.Exit(1)
+}
+
+// Installs ASDF_INSTALL_VERSION into ASDF_INSTALL_PATH, going through the
+// local tarball cache.
+func (app *AppState) commandAsdfInstall() {
+	installType := os.Getenv("ASDF_INSTALL_TYPE")
+	installVersion := os.Getenv("ASDF_INSTALL_VERSION")
+	installPath := os.Getenv("ASDF_INSTALL_PATH")
+
+	if installType == "ref" {
+		logger.errorf("Installing from a ref is not supported!\n")
+		os.Exit(1)
+	}
+
+	if installVersion == "" || installPath == "" {
+		logger.errorf("ASDF_INSTALL_VERSION and ASDF_INSTALL_PATH must be set!\n")
+		os.Exit(1)
+	}
+
+	item, ok := app.itemForPin(installVersion)
+	if !ok {
+		app.fail(&VersionNotFoundError{Name: installVersion})
+	}
+
+	// The install is extracted from the tarball, which may have been deleted
+	// after extracting the version for zig-toolchain itself.
+	if _, err := os.Stat(item.LocalPath); err != nil {
+		item.Downloaded = false
+	}
+
+	app.AssumeYes = true
+	app.commandDownloadItem(item)
+
+	tmp, err := os.MkdirTemp(path.Dir(installPath), ".zig-toolchain-asdf")
+	if err != nil {
+		app.fail(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	logger.infof("Extracting...")
+	if err = extractTarball(item.LocalPath, tmp); err != nil {
+		app.fail(err)
+	}
+	if err = fixToolchainPermissions(path.Join(tmp, tarballBaseName(item.LocalPath))); err != nil {
+		app.fail(err)
+	}
+	if !app.Config.KeepQuarantine {
+		if err = stripQuarantine(path.Join(tmp, tarballBaseName(item.LocalPath))); err != nil {
+			app.fail(err)
+		}
+	}
+	logger.infof("Done!\n")
+
+	if err = os.RemoveAll(installPath); err != nil {
+		app.fail(err)
+	}
+	if err = os.Rename(path.Join(tmp, tarballBaseName(item.LocalPath)), installPath); err != nil {
+		app.fail(err)
+	}
+
+	if err = os.MkdirAll(path.Join(installPath, "bin"), os.ModePerm); err != nil {
+		app.fail(err)
+	}
+	if err = os.Symlink(path.Join("..", "zig"), path.Join(installPath, "bin", "zig")); err != nil {
+		app.fail(err)
+	}
+}
+
+func (app *AppState) commandAsdfListBinPaths() {
+	fmt.Printf("bin\n")
+}
+
+func (app *AppState) commandAsdfExecEnv() {
+	if installPath := os.Getenv("ASDF_INSTALL_PATH"); installPath != "" {
+		fmt.Printf("export ZIG_LIB_DIR=%q\n", path.Join(installPath, "lib"))
+	}
+}
+
+// Writes an asdf/mise plugin delegating to zig-toolchain into dir.
+func (app *AppState) commandAsdfPlugin(dir string) {
+	err := os.MkdirAll(path.Join(dir, "bin"), os.ModePerm)
+	if err != nil {
+		app.fail(err)
+	}
+
+	for name, body := range asdfPluginScripts {
+		script := "#!/usr/bin/env bash\n\n" + body
+		err = os.WriteFile(path.Join(dir, "bin", name), []byte(script), 0755)
+		if err != nil {
+			app.fail(err)
+		}
+	}
+
+	logger.infof("Wrote asdf plugin to %s\n", dir)
+	logger.infof("Register it with `ln -s %s ~/.asdf/plugins/zig` or `mise plugin link zig %s`.\n", dir, dir)
+}
+
+func printAsdfUsageAndExit() {
+	fmt.Printf("USAGE: zig-toolchain asdf [COMMAND]\n\n")
+	fmt.Printf("COMMANDS:")
+	fmt.Printf("\n    plugin [DIR]\t Write an asdf/mise plugin using zig-toolchain as backend.")
+	fmt.Printf("\n    list-all\t\t List all installable versions.")
+	fmt.Printf("\n    latest-stable\t Print the latest stable version.")
+	fmt.Printf("\n    install\t\t Install ASDF_INSTALL_VERSION into ASDF_INSTALL_PATH.")
+	fmt.Printf("\n    list-bin-paths\t Print the bin paths of an installed version.")
+	fmt.Printf("\n    exec-env\t\t Print the environment for an installed version.")
+	fmt.Printf("\n\n")
+	os.Exit(0)
+}
+
+func (app *AppState) commandAsdf(args []string) {
+	if len(args) < 1 {
+		printAsdfUsageAndExit()
+	}
+
+	switch args[0] {
+	case "plugin":
+		if len(args) < 2 {
+			printAsdfUsageAndExit()
+		}
+		app.commandAsdfPlugin(args[1])
+	case "list-all":
+		app.commandAsdfListAll()
+	case "latest-stable":
+		app.commandAsdfLatestStable()
+	case "install":
+		app.commandAsdfInstall()
+	case "list-bin-paths":
+		app.commandAsdfListBinPaths()
+	case "exec-env":
+		app.commandAsdfExecEnv()
+	default:
+		printAsdfUsageAndExit()
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+const (
+	ZigRepoUrl   = "https://github.com/ziglang/zig"
+	GithubApiUrl = "https://api.github.com/repos/ziglang/zig"
+)
+
+// Length of the commit hashes in the names of dev builds.
+const devCommitLength = 9
+
+// Reports whether versions holds v.
+func containsVersion(versions []Version, v Version) bool {
+	for _, other := range versions {
+		if other.equal(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Installs item if needed and runs command with it first in PATH, as exec
+// does, without activating it. Returns true if the command succeeded.
+func (app *AppState) bisectTest(item *Item, command []string) (bool, error) {
+	dir := extractedDirForItem(item)
+	if !isExtracted(dir) {
+		if err := app.downloadItem(item); err != nil {
+			return false, err
+		}
+		if err := app.extractItem(item, nil); err != nil {
+			return false, err
+		}
+	}
+
+	logger.infof("Running `%s` with zig %s...\n", strings.Join(command, " "), item.Version.FullString())
+	cmd := exec.Command(resolveCommand(command[0], dir), command[1:]...)
+	cmd.Env = environWith(toolchainVars(item, dir))
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	// The command may well run zig-toolchain itself.
+	app.releaseLock()
+	err := cmd.Run()
+	lock, lockErr := acquireLock(true)
+	if lockErr != nil {
+		return false, lockErr
+	}
+	app.lock = lock
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Returns the dev builds there may be between good and bad, one per commit
+// in between as listed by GitHub. Dev builds are numbered by the commits
+// since the release, so the n-th commit after good is dev build good+n.
+// Not every commit has a build on the builds server.
+func (app *AppState) devBuildsBetween(good Version, bad Version) ([]Version, error) {
+	if err := app.networkError("list the commits between the builds"); err != nil {
+		return nil, err
+	}
+
+	result := []Version{}
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("%s/compare/%s...%s?per_page=100&page=%d", GithubApiUrl, good.Commit, bad.Commit, page)
+
+		var comparison githubComparison
+		err := app.withRetry("Listing the commits", func() error {
+			var err error
+			comparison, err = app.fetchComparison(url)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, commit := range comparison.Commits {
+			v := good
+			v.Build = good.Build + len(result) + 1
+			v.Prerelease = fmt.Sprintf("dev.%d", v.Build)
+			v.Commit = commit.Sha
+			if len(v.Commit) > devCommitLength {
+				v.Commit = v.Commit[:devCommitLength]
+			}
+			result = append(result, v)
+		}
+
+		if len(comparison.Commits) == 0 || len(result) >= comparison.TotalCommits {
+			break
+		}
+	}
+
+	// The last commit is bad itself.
+	if len(result) > 0 {
+		result = result[:len(result)-1]
+	}
+
+	return result, nil
+}
+
+type githubComparison struct {
+	TotalCommits int `json:"total_commits"`
+	Commits      []struct {
+		Sha string `json:"sha"`
+	} `json:"commits"`
+}
+
+// Fetches a comparison of two commits from the GitHub API. Our credentials
+// are not for GitHub, only GITHUB_TOKEN is sent, to raise the rate limit.
+func (app *AppState) fetchComparison(url string) (githubComparison, error) {
+	comparison := githubComparison{}
+
+	ctx, watchBody, cancel := app.requestContext()
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return comparison, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := app.httpClient().Do(req)
+	if err != nil {
+		return comparison, err
+	}
+	defer resp.Body.Close()
+
+	if err = checkResponseStatus(resp); err != nil {
+		return comparison, err
+	}
+
+	err = json.NewDecoder(watchBody(resp.Body)).Decode(&comparison)
+	return comparison, err
+}
+
+// Returns the item of the dev build v, looking it up on the builds server if
+// it isn't known.
+func (app *AppState) bisectItem(v Version) (*Item, error) {
+	if item, ok := app.GetItemByVersion(v); ok {
+		return item, nil
+	}
+
+	return app.historicalDevItem(v)
+}
+
+// Binary-searches the dev builds strictly between good and bad for the first
+// one on which command fails. Besides the known builds, the nightlies of the
+// commits in between are tried when the commits of good and bad are known.
+// Nothing is activated, so the active version is left as it was.
+func (app *AppState) commandBisect(good Version, bad Version, command []string) {
+	if !good.lessThan(bad) {
+		logger.errorf("The good version must be older than the bad version!\n")
+		os.Exit(1)
+	}
+
+	// Known builds tell the commits of versions given without one.
+	if item, ok := app.GetItemByVersion(good); ok && good.Commit == "" {
+		good = item.Version
+	}
+	if item, ok := app.GetItemByVersion(bad); ok && bad.Commit == "" {
+		bad = item.Version
+	}
+
+	candidates := []Version{}
+	for i := 0; i < len(app.Items); i++ {
+		item := &app.Items[i]
+		if item.Custom || !item.Version.Dev || !(item.Downloaded || item.Indexed) {
+			continue
+		}
+		if good.lessThan(item.Version) && item.Version.lessThan(bad) {
+			candidates = append(candidates, item.Version)
+		}
+	}
+
+	// Dev builds are only numbered alike after the same release.
+	sameRelease := good.Major == bad.Major && good.Minor == bad.Minor && good.Patch == bad.Patch
+	if sameRelease && good.Dev && bad.Dev && good.Commit != "" && bad.Commit != "" {
+		builds, err := app.devBuildsBetween(good, bad)
+		if err != nil {
+			logger.warnf("Only trying the known dev builds, failed to list the commits in between: %s\n", err)
+		}
+		for _, v := range builds {
+			if !containsVersion(candidates, v) {
+				candidates = append(candidates, v)
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].lessThan(candidates[j])
+	})
+
+	if len(candidates) == 0 {
+		logger.errorf("No known dev builds between %s and %s.\n", good.String(), bad.String())
+		os.Exit(1)
+	}
+
+	// Indices into candidates, where -1 is the good version and
+	// len(candidates) is the bad version. Commits without a build are
+	// dropped as they are found.
+	lo, hi := -1, len(candidates)
+	for hi-lo > 1 {
+		mid := (lo + hi) / 2
+		item, err := app.bisectItem(candidates[mid])
+		var notFoundErr *VersionNotFoundError
+		if errors.As(err, &notFoundErr) {
+			logger.debugf("There is no build of %s, skipping it\n", candidates[mid].FullString())
+			candidates = append(candidates[:mid], candidates[mid+1:]...)
+			hi--
+			continue
+		} else if err != nil {
+			app.fail(err)
+		}
+
+		logger.infof("\nBisecting: %d build(s) left to test\n", hi-lo-1)
+		ok, err := app.bisectTest(item, command)
+		if err != nil {
+			app.fail(err)
+		}
+		if ok {
+			logger.infof("==> %s is good\n", candidates[mid].FullString())
+			lo = mid
+		} else {
+			logger.infof("==> %s is bad\n", candidates[mid].FullString())
+			hi = mid
+		}
+	}
+
+	lastGood, firstBad := good, bad
+	if lo >= 0 {
+		lastGood = candidates[lo]
+	}
+	if hi < len(candidates) {
+		firstBad = candidates[hi]
+	}
+
+	fmt.Printf("\n")
+	if hi == len(candidates) {
+		fmt.Printf("First bad build: %s (no known builds in between were bad)\n", firstBad.FullString())
+	} else {
+		fmt.Printf("First bad build: %s\n", firstBad.FullString())
+	}
+	fmt.Printf("Last good build: %s\n", lastGood.FullString())
+
+	// Nightlies are several commits apart, the rest is up to git.
+	if lastGood.Commit != "" && firstBad.Commit != "" {
+		fmt.Printf("Changes in between: %s/compare/%s...%s\n", ZigRepoUrl, lastGood.Commit, firstBad.Commit)
+	}
+	app.notify(fmt.Sprintf("Bisect finished: first bad build is %s", firstBad.FullString()))
+}
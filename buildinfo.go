@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+// Version of zig-toolchain itself, the commit it was built from and when,
+// set by release builds with e.g.:
+//
+//	go build -ldflags "-X main.toolVersion=1.2.0 -X main.toolCommit=$(git rev-parse HEAD) -X main.toolBuildDate=$(date -u +%Y-%m-%d)"
+//
+// Otherwise they are taken from the build info Go embeds, which has the
+// module version with `go install` and the commit when built from a checkout.
+var (
+	toolVersion   = ""
+	toolCommit    = ""
+	toolBuildDate = ""
+)
+
+type ToolBuildInfo struct {
+	Version string
+	Commit  string
+	Date    string
+	// Whether the checkout had uncommitted changes.
+	Modified bool
+}
+
+func toolBuildInfo() ToolBuildInfo {
+	info := ToolBuildInfo{Version: toolVersion, Commit: toolCommit, Date: toolBuildDate}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && build.Main.Version != "" && build.Main.Version != "(devel)" {
+			info.Version = strings.TrimPrefix(build.Main.Version, "v")
+		}
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+
+	return info
+}
+
+// Prints the version of zig-toolchain, e.g.
+// `zig-toolchain 1.2.0 (commit 3f2a1b9c0d4e, built 2024-05-01)`.
+func commandToolVersion() {
+	info := toolBuildInfo()
+
+	details := []string{}
+	if info.Commit != "" {
+		commit := info.Commit
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+		if info.Modified {
+			commit += "-dirty"
+		}
+		details = append(details, "commit "+commit)
+	}
+	if info.Date != "" {
+		details = append(details, "built "+info.Date)
+	}
+
+	fmt.Printf("zig-toolchain %s", info.Version)
+	if len(details) > 0 {
+		fmt.Printf(" (%s)", strings.Join(details, ", "))
+	}
+	fmt.Printf("\n")
+}
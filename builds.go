@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// Location of the nightly builds, including the ones that are no longer
+	// in the index.
+	BuildsUrl = "https://ziglang.org/builds/"
+)
+
+// Returns the candidate URLs of the host's tarball of the dev build v on the
+// builds server. Tarball names changed from `zig-<os>-<arch>-<version>` to
+// `zig-<arch>-<os>-<version>` at some point, so both are tried.
+func buildUrls(v Version) []string {
+	hostOs := getHostOs()
+	ext := tarballExt()
+
+	urls := []string{}
+	for _, hostArch := range hostArchNames() {
+		urls = append(urls,
+			fmt.Sprintf("%szig-%s-%s-%s%s", BuildsUrl, hostArch, hostOs, v.FullString(), ext),
+			fmt.Sprintf("%szig-%s-%s-%s%s", BuildsUrl, hostOs, hostArch, v.FullString(), ext),
+		)
+	}
+
+	return urls
+}
+
+// Returns the size of the file at url, if it exists.
+func (app *AppState) probeUrl(url string) (int64, error) {
+	ctx, _, cancel := app.requestContext()
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "HEAD", url, nil)
+	if err != nil {
+		return 0, err
+	}
+	app.authorizeRequest(req)
+
+	res, err := app.httpClient().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	res.Body.Close()
+
+	if err = checkResponseStatus(res); err != nil {
+		return 0, err
+	}
+
+	return res.ContentLength, nil
+}
+
+// Error for a tarball from the builds server that doesn't hold the build it
+// was looked up for.
+type BuildMismatchError struct {
+	Path     string
+	Expected string
+	Actual   string
+}
+
+func (e *BuildMismatchError) Error() string {
+	return fmt.Sprintf("%s holds %s instead of zig %s", e.Path, e.Actual, e.Expected)
+}
+
+// Checks that the tarball at tarballPath holds the dev build v, going by the
+// name of its top-level directory, e.g. zig-x86_64-linux-0.14.0-dev.2345+abcdef12.
+func checkBuildVersion(tarballPath string, v Version) error {
+	entries, err := tarballEntries(tarballPath)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return &BuildMismatchError{Path: tarballPath, Expected: v.FullString(), Actual: "nothing"}
+	}
+
+	for _, entry := range entries {
+		dir := strings.SplitN(strings.TrimPrefix(entry, "./"), "/", 2)[0]
+		if !strings.HasSuffix(dir, "-"+v.FullString()) {
+			return &BuildMismatchError{Path: tarballPath, Expected: v.FullString(), Actual: dir}
+		}
+	}
+
+	return nil
+}
+
+// Looks up a dev build that is no longer in the index on the builds server.
+// The item is not added to the items, so that it doesn't move the ones
+// pointers are held to. Only exact versions, including the commit, can be
+// found this way.
+func (app *AppState) historicalDevItem(v Version) (*Item, error) {
+	if !v.Dev {
+		return nil, &VersionNotFoundError{Name: v.FullString()}
+	}
+	if v.Commit == "" {
+		return nil, &VersionNotFoundError{Name: v.FullString(), Hint: "older dev builds can only be found by their full version, e.g. 0.14.0-dev.2345+abcdef12"}
+	}
+
+	if err := app.networkError(fmt.Sprintf("look up zig %s", v.FullString())); err != nil {
+		return nil, err
+	}
+
+	var err error
+	for _, url := range buildUrls(v) {
+		var size int64
+		err = app.withRetry("Looking up the build", func() error {
+			size, err = app.probeUrl(url)
+			return err
+		})
+		if err != nil {
+			continue
+		}
+
+		item := &Item{}
+		item.Version = v
+		item.RemoteUrl = app.Config.rewriteUrl(url)
+		item.OriginalUrl = url
+		item.LocalPath = localTarballPathFromUrl(url)
+		item.Size = size
+		item.FromBuilds = true
+		return item, nil
+	}
+
+	var statusErr *HttpStatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return nil, &VersionNotFoundError{Name: v.FullString()}
+	}
+	return nil, err
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	// Release channels, usable wherever a version is: the newest tagged
+	// release (the stable alias), the newest nightly, and the newest version
+	// nominated by the Mach engine.
+	ChannelStable = AliasStable
+	ChannelMaster = "master"
+	ChannelMach   = "mach"
+
+	// Index of the zig versions nominated by Mach, in the format of the
+	// ziglang.org one.
+	MachIndexUrl = "https://machengine.org/zig/index.json"
+	// Entry of the Mach index for the latest nomination.
+	machLatestKey = "mach-latest"
+)
+
+func isChannel(name string) bool {
+	return name == ChannelStable || name == ChannelMaster || name == ChannelMach
+}
+
+// Returns the newest item of the given channel.
+func (app *AppState) channelItem(channel string) (*Item, bool) {
+	switch channel {
+	case ChannelStable:
+		return app.latestStableItem()
+	case ChannelMaster:
+		return app.masterItem()
+	case ChannelMach:
+		return app.machItem()
+	}
+
+	return nil, false
+}
+
+// Returns the latest version nominated by Mach. The Mach index is only
+// fetched the first time it's needed, and never with an index snapshot, which
+// would defeat the point of the snapshot.
+func (app *AppState) machItem() (*Item, bool) {
+	if !app.machFetched && app.IndexFile == "" {
+		app.machFetched = true
+		if err := app.fetchMachVersion(); err != nil {
+			logger.warnf("Failed to fetch the Mach index: %s\n", err)
+		}
+	}
+
+	if app.machVersion == nil {
+		return nil, false
+	}
+
+	return app.GetItemByVersion(*app.machVersion)
+}
+
+// Fetches the latest Mach nomination, adding it to the items if the ziglang.org
+// index doesn't have it, which is the case for older dev builds. Only used
+// when the Mach index isn't one of the index sources.
+func (app *AppState) fetchMachVersion() error {
+	if err := app.networkError("fetch the Mach index"); err != nil {
+		return err
+	}
+
+	var index *ZigIndex
+	err := app.withRetry("Fetching the Mach index", func() error {
+		var err error
+		index, err = app.fetchIndexFrom(MachIndexUrl)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	entry, ok := index.Entries[machLatestKey]
+	if !ok || entry.Version == "" {
+		return fmt.Errorf("no %s entry in %s", machLatestKey, MachIndexUrl)
+	}
+
+	item, ok := app.machIndexItem(machLatestKey, entry)
+	if !ok {
+		return fmt.Errorf("zig %s, nominated by Mach, has no build for %s-%s", entry.Version, getHostArch(), getHostOs())
+	}
+	app.machVersion = &item.Version
+
+	if existing, ok := app.GetItemByVersion(item.Version); ok {
+		if !existing.Indexed {
+			item.Downloaded = existing.Downloaded
+			item.Current = existing.Current
+			item.LocalPath = existing.LocalPath
+			*existing = item
+		}
+		return nil
+	}
+
+	app.Items = append(app.Items, item)
+	return nil
+}
+
+// Returns the item for the host of an entry of the Mach index.
+func (app *AppState) machIndexItem(key string, entry ZigIndexEntry) (Item, bool) {
+	item, ok := app.indexItem(key, entry)
+	if !ok {
+		return item, false
+	}
+	item.Master = false
+	item.SourceLabel = ChannelMach
+
+	// The zig version is the one in the tarball name, entries may carry the
+	// Mach version instead (e.g. 2024.10.0-mach).
+	if v, err := parseTarballName(path.Base(item.LocalPath)); err == nil {
+		item.Version = *v
+	}
+
+	return item, true
+}
+
+// Reports whether name is a version nominated by Mach, e.g. 2024.10.0-mach.
+func isNomination(name string) bool {
+	return strings.HasSuffix(name, "-mach") && name != machLatestKey
+}
+
+// Adds the versions nominated by Mach from its index, and records the
+// nominations of the versions that are in the ziglang.org index too.
+func (app *AppState) addMachItems(index *ZigIndex) {
+	for key, entry := range index.Entries {
+		if key != machLatestKey && !isNomination(key) {
+			continue
+		}
+
+		item, ok := app.machIndexItem(key, entry)
+		if !ok {
+			continue
+		}
+
+		existing, ok := app.GetItemByVersion(item.Version)
+		if !ok {
+			app.Items = append(app.Items, item)
+			existing = &app.Items[len(app.Items)-1]
+		}
+
+		if key == machLatestKey {
+			version := item.Version
+			app.machVersion = &version
+			app.machFetched = true
+		} else {
+			existing.Nominations = append(existing.Nominations, key)
+			sort.Strings(existing.Nominations)
+		}
+	}
+}
+
+// Returns the item nominated by Mach under the given name.
+func (app *AppState) itemForNomination(name string) (*Item, bool) {
+	if !isNomination(name) {
+		return nil, false
+	}
+
+	for i := 0; i < len(app.Items); i++ {
+		for _, nomination := range app.Items[i].Nominations {
+			if nomination == name {
+				return &app.Items[i], true
+			}
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type ChecksumError struct {
+	Path     string
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("checksum mismatch for %s: expected %s, got %s", e.Path, e.Expected, e.Actual)
+}
+
+func fileSha256(p string) (string, error) {
+	file, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func checkSha256(p string, actual string, expected string) error {
+	if !strings.EqualFold(actual, expected) {
+		return &ChecksumError{Path: p, Expected: expected, Actual: actual}
+	}
+
+	return nil
+}
+
+// Verifies the local tarball of item against the shasum from the index.
+// Items without a known shasum (e.g. not in the index) pass.
+func (app *AppState) verifyTarball(item *Item) error {
+	if item.Shasum == "" {
+		return nil
+	}
+
+	actual, err := fileSha256(item.LocalPath)
+	if err != nil {
+		return err
+	}
+
+	if err = checkSha256(item.LocalPath, actual, item.Shasum); err != nil {
+		return err
+	}
+
+	app.emitProgress(ProgressEvent{Event: "checksum_verified", Version: item.Version.FullString(), Path: item.LocalPath})
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestVerifyTarballAgainstStoredShasum(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := ensureDirectories(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("zig 0.11.0")
+	sum := sha256.Sum256(data)
+	tarball := localDirPath("tarballs", fmt.Sprintf("zig-%s-%s-0.11.0%s", hostArchNames()[0], getHostOs(), tarballExt()))
+
+	tests := []struct {
+		shasum   string
+		contents string
+		mismatch bool
+	}{
+		{hex.EncodeToString(sum[:]), string(data), false},
+		{hex.EncodeToString(sum[:]), "zig 0.11.1", true},
+		// Tarballs downloaded without a shasum can't be verified.
+		{"", "zig 0.11.1", false},
+	}
+
+	for _, test := range tests {
+		if err := os.WriteFile(tarball, []byte(test.contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+		state := &State{Versions: map[string]*VersionState{}}
+		v := state.entry(tarballBaseName(tarball), tarball)
+		v.Version = "0.11.0"
+		v.HasTarball = true
+		v.Size = int64(len(test.contents))
+		v.Shasum = test.shasum
+		if err := state.Save(); err != nil {
+			t.Fatal(err)
+		}
+
+		// Offline, the shasum only comes from the state.
+		app := NewAppState()
+		app.loadItems(nil)
+		item, ok := app.GetItemByVersion(Version{Minor: 11})
+		if !ok || !item.Downloaded {
+			t.Fatalf("0.11.0 is not loaded from the state")
+		}
+
+		err := app.verifyTarball(item)
+		var checksumErr *ChecksumError
+		if mismatch := errors.As(err, &checksumErr); mismatch != test.mismatch {
+			t.Errorf("shasum %q, contents %q: verifyTarball() = %v, want a mismatch: %v", test.shasum, test.contents, err, test.mismatch)
+		}
+		if err != nil && checksumErr == nil {
+			t.Errorf("shasum %q, contents %q: verifyTarball() = %v", test.shasum, test.contents, err)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Reports whether the server supports ranged requests for url, and if so
+// the size of the file.
+func (app *AppState) supportsRanges(url string) (int64, bool) {
+	req, err := http.NewRequestWithContext(app.context(), "HEAD", url, nil)
+	if err != nil {
+		return 0, false
+	}
+	app.authorizeRequest(req)
+
+	res, err := app.httpClient().Do(req)
+	if err != nil {
+		return 0, false
+	}
+	res.Body.Close()
+
+	if checkResponseStatus(res) != nil || res.Header.Get("Accept-Ranges") != "bytes" || res.ContentLength <= 0 {
+		return 0, false
+	}
+
+	return res.ContentLength, true
+}
+
+// Writer writing at increasing offsets of an io.WriterAt.
+type offsetWriter struct {
+	w      io.WriterAt
+	offset int64
+}
+
+func (o *offsetWriter) Write(p []byte) (int, error) {
+	n, err := o.w.WriteAt(p, o.offset)
+	o.offset += int64(n)
+	return n, err
+}
+
+// Writer counting the bytes written through it into a shared counter.
+type countingWriter struct {
+	w       io.Writer
+	counter *int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	atomic.AddInt64(c.counter, int64(n))
+	return n, err
+}
+
+func (app *AppState) downloadChunk(url string, file *os.File, start int64, end int64, counter *int64) error {
+	ctx, watchBody, cancel := app.requestContext()
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	app.authorizeRequest(req)
+
+	res, err := app.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if err = checkResponseStatus(res); err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("GET %s: server ignored the range request", url)
+	}
+
+	written, err := io.Copy(&countingWriter{w: &offsetWriter{w: file, offset: start}, counter: counter}, watchBody(res.Body))
+	if err != nil {
+		return err
+	}
+	if written != end-start+1 {
+		return fmt.Errorf("GET %s: short chunk at offset %d", url, start)
+	}
+
+	return nil
+}
+
+// Downloads the tarball of item with Config.Concurrency parallel ranged
+// requests, each writing its own part of the temporary file.
+func (app *AppState) downloadTarballChunked(item Item, size int64) error {
+	n := int64(app.Config.Concurrency)
+	logger.infof("Downloading tarball %s (%d connections)...\n", item.RemoteUrl, n)
+
+	version := item.Version.FullString()
+	app.emitProgress(ProgressEvent{Event: "download_started", Version: version, Url: item.RemoteUrl, Total: size})
+
+	file, err := os.CreateTemp(path.Dir(item.LocalPath), ".download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err = file.Truncate(size); err != nil {
+		file.Close()
+		return err
+	}
+
+	var downloaded int64
+	bar := app.newProgressBar(size)
+	stop := make(chan struct{})
+	go func() {
+		defer app.recoverPanic()
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				read := atomic.LoadInt64(&downloaded)
+				bar.update(read)
+				app.emitProgress(ProgressEvent{Event: "download_progress", Version: version, Bytes: read, Total: size})
+			}
+		}
+	}()
+
+	chunkSize := (size + n - 1) / n
+	errs := make(chan error, n)
+	var wg sync.WaitGroup
+	for start := int64(0); start < size; start += chunkSize {
+		end := start + chunkSize - 1
+		if end >= size {
+			end = size - 1
+		}
+
+		wg.Add(1)
+		go func(start int64, end int64) {
+			defer app.recoverPanic()
+			defer wg.Done()
+			errs <- app.downloadChunk(item.RemoteUrl, file, start, end, &downloaded)
+		}(start, end)
+	}
+	wg.Wait()
+	close(stop)
+	close(errs)
+	bar.finish(atomic.LoadInt64(&downloaded))
+
+	if closeErr := file.Close(); closeErr != nil {
+		return closeErr
+	}
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	sum, err := fileSha256(file.Name())
+	if err != nil {
+		return err
+	}
+
+	return app.finishDownload(item, file.Name(), sum, size)
+}
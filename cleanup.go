@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// Partial downloads that haven't been written to for this long belong
+	// to a process that was killed, rather than to one still running.
+	partialStaleAfter = 10 * time.Minute
+)
+
+// Reports whether name is a temporary file or directory of an in-progress
+// download or extraction.
+func isPartialArtifact(name string) bool {
+	return strings.HasPrefix(name, ".download-") || strings.HasPrefix(name, ".extract")
+}
+
+// Removes the stale partial downloads and extractions left behind by
+// interrupted runs.
+func cleanupPartialArtifacts() {
+	for _, root := range []string{localDirPath("tarballs"), localDirPath("src"), localDirPath("versions")} {
+		filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || !isPartialArtifact(d.Name()) {
+				return nil
+			}
+
+			if info, err := d.Info(); err == nil && time.Since(info.ModTime()) > partialStaleAfter {
+				os.RemoveAll(p)
+			}
+
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+	}
+}
+
+// Reports whether the local tarball of an indexed item is incomplete, i.e.
+// its size doesn't match the index. Such tarballs can be left behind by
+// older versions of this tool, which wrote downloads in place.
+func (item *Item) hasIncompleteTarball(info fs.FileInfo) bool {
+	return item.Indexed && item.Size > 0 && info.Size() != item.Size
+}
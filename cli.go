@@ -0,0 +1,466 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// A flag accepted on the command line. Flags with a Value placeholder take a
+// value, the others are booleans.
+type FlagSpec struct {
+	Name        string
+	Value       string
+	Description string
+}
+
+// A command, with its usage and the flags it accepts besides the global ones.
+type CommandSpec struct {
+	Id          int
+	Name        string
+	Aliases     []string
+	Usage       []string
+	Description string
+	Flags       []FlagSpec
+}
+
+// Flags accepted by every command.
+var globalFlags = []FlagSpec{
+	{"help", "", "Show the usage of the command."},
+	{"yes", "", "Don't ask for confirmation."},
+	{"force", "", "Go ahead even if it's not safe, e.g. on a checksum mismatch."},
+	{"json", "", "Print JSON instead of text."},
+	{"format", "TEMPLATE", "Print each version with a Go template."},
+	{"color", "WHEN", "Color the output: auto, always or never."},
+	{"quiet", "", "Only print errors (-q)."},
+	{"verbose", "", "Print debug messages (-v), or every step (-vv)."},
+	{"notify", "", "Show a desktop notification when done."},
+	{"no-network", "", "Fail instead of accessing the network."},
+	{"offline", "", "Work from local data only."},
+	{"index-url", "URL", "Use another release index."},
+	{"index-file", "FILE", "Use an index snapshot instead of fetching the index."},
+	{"progress", "FORMAT", "Report progress as json on stderr."},
+	{"override-policy", "", "Ignore the version policy."},
+	{"stream", "", "Extract tarballs while downloading them."},
+	{"keep-quarantine", "", "Keep the macOS quarantine attribute."},
+	{"retries", "N", "Number of attempts for network operations."},
+	{"timeout", "DURATION", "Deadline for the whole command."},
+	{"request-timeout", "DURATION", "Longest an HTTP request may stall (default 1m)."},
+	{"concurrency", "N", "Number of connections per download."},
+	{"proxy", "URL", "Proxy to use for HTTP requests."},
+	{"cacert", "FILE", "Additional CA certificates to trust."},
+	{"insecure", "", "Don't verify TLS certificates."},
+	{"ipv4", "", "Only connect over IPv4."},
+	{"ipv6", "", "Only connect over IPv6."},
+}
+
+var commands = []CommandSpec{
+	{
+		Id:   CommandDownload,
+		Name: "download",
+		Usage: []string{
+			"download [VERSION...]",
+			"download --src [VERSION]",
+			"download --bootstrap [VERSION]",
+			"download --target [TARGET] [VERSION]",
+		},
+		Description: "Download a zig version.",
+		Flags: []FlagSpec{
+			{"dry-run", "", "Only print what would be downloaded."},
+			{"parallel", "", "Download several versions in parallel."},
+			{ArchiveSource, "", "Download the source archive."},
+			{ArchiveBootstrap, "", "Download the bootstrap archive."},
+			{"target", "TARGET", "Download the tarball for another platform."},
+		},
+	},
+	{
+		Id:          CommandList,
+		Name:        "list",
+		Usage:       []string{"list [--platforms] [--stable-only|--dev-only] [--downloaded] [--since VERSION] [--limit N] [--sort version|date] [--reverse]"},
+		Description: "List remote versions.",
+		Flags: []FlagSpec{
+			{"platforms", "", "Show the targets of each version."},
+			{"stable-only", "", "Only show tagged releases."},
+			{"dev-only", "", "Only show dev builds."},
+			{"downloaded", "", "Only show downloaded versions."},
+			{"since", "VERSION", "Only show versions since VERSION."},
+			{"limit", "N", "Show at most N versions."},
+			{"sort", "ORDER", "Sort by version (the default) or release date, newest first."},
+			{"reverse", "", "Show the oldest versions first."},
+		},
+	},
+	{
+		Id:          CommandShow,
+		Name:        "show",
+		Usage:       []string{"show"},
+		Description: "List local versions.",
+	},
+	{
+		Id:          CommandActivate,
+		Name:        "activate",
+		Usage:       []string{"activate [--local|--global] [VERSION]"},
+		Description: "Activate a given zig version.",
+		Flags: []FlagSpec{
+			{"dry-run", "", "Only print what would be downloaded, extracted and linked."},
+			{"link-mode", "MODE", "How to link zig: symlink, hardlink, copy, shim or auto."},
+			{"delete-tarball", "", "Delete the tarball once extracted."},
+			{"local", "", "Pin the version for the project in the working directory instead."},
+			{"global", "", "Make the version the global default (the default)."},
+		},
+	},
+	{
+		Id:          CommandDefault,
+		Name:        "default",
+		Usage:       []string{"default [VERSION]"},
+		Description: "Set the global default zig version, or print it.",
+		Flags: []FlagSpec{
+			{"dry-run", "", "Only print what would be downloaded, extracted and linked."},
+			{"link-mode", "MODE", "How to link zig: symlink, hardlink, copy, shim or auto."},
+			{"delete-tarball", "", "Delete the tarball once extracted."},
+		},
+	},
+	{
+		Id:          CommandDeactivate,
+		Name:        "deactivate",
+		Usage:       []string{"deactivate"},
+		Description: "Deactivate the current active version. Removes the symlink to the zig binary.",
+	},
+	{
+		Id:          CommandPin,
+		Name:        "pin",
+		Usage:       []string{"pin [--format FORMAT] [VERSION]"},
+		Description: "Pin the current directory to a zig version.",
+		Flags: []FlagSpec{
+			{"format", "FORMAT", "File to write: zig-version (.zig-version, the default) or tool-versions (.tool-versions)."},
+		},
+	},
+	{
+		Id:          CommandWhy,
+		Name:        "why",
+		Usage:       []string{"why [VERSION]"},
+		Description: "Show which known projects still need a zig version.",
+	},
+	{
+		Id:          CommandGc,
+		Name:        "gc",
+		Usage:       []string{"gc [--dry-run] [--tarballs]"},
+		Description: "Remove versions that are not active or pinned by a known project, and the tarballs of extracted versions.",
+		Flags: []FlagSpec{
+			{"dry-run", "", "Only print what would be removed."},
+			{"tarballs", "", "Only remove the tarballs of extracted versions."},
+		},
+	},
+	{
+		Id:          CommandSmokeTest,
+		Name:        "smoke-test",
+		Usage:       []string{"smoke-test [VERSION]"},
+		Description: "Build and run a hello world program with a zig version.",
+	},
+	{
+		Id:          CommandBisect,
+		Name:        "bisect",
+		Usage:       []string{"bisect --good [VERSION] --bad [VERSION] -- [COMMAND]"},
+		Description: "Find the first dev build on which a command fails.",
+		Flags: []FlagSpec{
+			{"good", "VERSION", "A version on which the command succeeds."},
+			{"bad", "VERSION", "A version on which the command fails."},
+		},
+	},
+	{
+		Id:          CommandAsdf,
+		Name:        "asdf",
+		Usage:       []string{"asdf [COMMAND]"},
+		Description: "Act as the backend of an asdf/mise plugin.",
+	},
+	{
+		Id:          CommandExec,
+		Name:        "exec",
+		Usage:       []string{"exec [--strict] [VERSION] -- [COMMAND]"},
+		Description: "Run a command with the given zig version, or the one pinned by the current project.",
+		Flags: []FlagSpec{
+			{"strict", "", "Fail if the pinned version isn't installed."},
+		},
+	},
+	{
+		Id:          CommandLink,
+		Name:        "link",
+		Usage:       []string{"link [NAME] [PATH]", "link --remove [NAME]"},
+		Description: "Register a custom zig build as a named toolchain.",
+		Flags: []FlagSpec{
+			{"remove", "NAME", "Unregister a custom toolchain."},
+		},
+	},
+	{
+		Id:          CommandInstall,
+		Name:        "install",
+		Usage:       []string{"install --file [TARBALL] [--activate] [--delete-tarball]"},
+		Description: "Install a zig version from a local tarball.",
+		Flags: []FlagSpec{
+			{"file", "TARBALL", "The tarball to install."},
+			{"activate", "", "Activate the version once installed."},
+			{"delete-tarball", "", "Delete the tarball once extracted."},
+		},
+	},
+	{
+		Id:          CommandDedupe,
+		Name:        "dedupe",
+		Usage:       []string{"dedupe"},
+		Description: "Hardlink identical files across extracted versions.",
+	},
+	{
+		Id:          CommandRemove,
+		Name:        "remove",
+		Aliases:     []string{"uninstall"},
+		Usage:       []string{"remove [VERSION]", "remove --all-dev"},
+		Description: "Remove a downloaded zig version.",
+		Flags: []FlagSpec{
+			{"dry-run", "", "Only print what would be deleted."},
+			{"all-dev", "", "Remove all downloaded dev builds."},
+		},
+	},
+	{
+		Id:          CommandPrune,
+		Name:        "prune",
+		Usage:       []string{"prune [--dry-run] [--keep-dev N] [--keep-days N]"},
+		Description: "Remove downloaded versions according to the retention policy.",
+		Flags: []FlagSpec{
+			{"dry-run", "", "Only print what would be removed."},
+			{"keep-dev", "N", "Number of dev builds to keep."},
+			{"keep-days", "N", "Keep the versions used within this many days."},
+		},
+	},
+	{
+		Id:          CommandUpgrade,
+		Name:        "upgrade",
+		Usage:       []string{"upgrade [stable|master|mach] [--remove-old]"},
+		Description: "Activate the newest version of a channel (stable, master or mach).",
+		Flags: []FlagSpec{
+			{"dry-run", "", "Only print what would be downloaded, linked and deleted."},
+			{"remove-old", "", "Remove the version that was replaced."},
+		},
+	},
+	{
+		Id:          CommandOutdated,
+		Name:        "outdated",
+		Usage:       []string{"outdated"},
+		Description: "Show whether the active version is behind the latest stable or master.",
+	},
+	{
+		Id:          CommandRollback,
+		Name:        "rollback",
+		Usage:       []string{"rollback"},
+		Description: "Reactivate the previously active version.",
+	},
+	{
+		Id:          CommandAlias,
+		Name:        "alias",
+		Usage:       []string{"alias [NAME] [VERSION]", "alias --remove [NAME]"},
+		Description: "Give a zig version a name.",
+		Flags: []FlagSpec{
+			{"remove", "NAME", "Remove an alias."},
+		},
+	},
+	{
+		Id:          CommandAdopt,
+		Name:        "adopt",
+		Usage:       []string{"adopt [PATH] [--name NAME]"},
+		Description: "Register an externally installed zig as a named toolchain.",
+		Flags: []FlagSpec{
+			{"name", "NAME", "Name of the toolchain, the directory name by default."},
+		},
+	},
+	{
+		Id:          CommandResolve,
+		Name:        "resolve",
+		Usage:       []string{"resolve [RANGE]"},
+		Description: "Print the newest version matching a version range.",
+	},
+	{
+		Id:          CommandUpdate,
+		Name:        "update",
+		Usage:       []string{"update"},
+		Description: "Refresh the cached release index.",
+	},
+	{
+		Id:          CommandIndex,
+		Name:        "index",
+		Usage:       []string{"index export"},
+		Description: "Export a snapshot of the index.",
+	},
+	{
+		Id:          CommandInfo,
+		Name:        "info",
+		Usage:       []string{"info [VERSION]"},
+		Description: "Show everything known about a version.",
+	},
+	{
+		Id:          CommandCompletion,
+		Name:        "completion",
+		Usage:       []string{"completion bash|zsh|fish"},
+		Description: "Print the shell completion script.",
+		Flags: []FlagSpec{
+			{"versions", "WHICH", "Print the versions to complete: all or local."},
+		},
+	},
+	{
+		Id:          CommandCurrent,
+		Name:        "current",
+		Usage:       []string{"current [--explain]"},
+		Description: "Print the active version.",
+		Flags: []FlagSpec{
+			{"explain", "", "Show where the version to use comes from, in order of precedence."},
+		},
+	},
+	{
+		Id:          CommandWhich,
+		Name:        "which",
+		Usage:       []string{"which [--lib]"},
+		Description: "Print the path of the active zig binary.",
+		Flags: []FlagSpec{
+			{"lib", "", "Print the path of its lib directory instead."},
+		},
+	},
+	{
+		Id:          CommandSearch,
+		Name:        "search",
+		Usage:       []string{"search QUERY"},
+		Description: "Search the indexed and local versions by version, date or commit.",
+	},
+	{
+		Id:          CommandEnv,
+		Name:        "env",
+		Usage:       []string{"env [--shell SHELL] [VERSION]"},
+		Description: "Print the environment for using the active or given zig version, to eval.",
+		Flags: []FlagSpec{
+			{"shell", "SHELL", "Shell to print it for: bash, zsh, sh, fish, powershell or cmd."},
+		},
+	},
+	{
+		Id:          CommandShell,
+		Name:        "shell",
+		Usage:       []string{"shell [--print] VERSION"},
+		Description: "Start a shell using the given zig version, without activating it.",
+		Flags: []FlagSpec{
+			{"print", "", "Print the environment to eval instead, e.g. eval \"$(zig-toolchain shell --print 0.13.0)\"."},
+		},
+	},
+}
+
+func findCommand(name string) (*CommandSpec, bool) {
+	for i := range commands {
+		if commands[i].Name == name {
+			return &commands[i], true
+		}
+		for _, alias := range commands[i].Aliases {
+			if alias == name {
+				return &commands[i], true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func findFlag(flags []FlagSpec, name string) (*FlagSpec, bool) {
+	for i := range flags {
+		if flags[i].Name == name {
+			return &flags[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// Parses the arguments of the command, failing on flags it doesn't accept.
+func (spec *CommandSpec) parseArgs(args []string) *Args {
+	valueFlags := []string{}
+	for _, flag := range spec.Flags {
+		if flag.Value != "" {
+			valueFlags = append(valueFlags, flag.Name)
+		}
+	}
+
+	result := ParseArgs(args, valueFlags...)
+	for name := range result.Flags {
+		flag, ok := findFlag(spec.Flags, name)
+		if !ok {
+			flag, ok = findFlag(globalFlags, name)
+		}
+		if !ok {
+			usageError(fmt.Sprintf("Unknown flag --%s for %s.", name, spec.Name), spec.Name)
+		}
+		if flag.Value != "" && result.Flags[name] == "" {
+			usageError(fmt.Sprintf("--%s needs a value.", name), spec.Name)
+		}
+	}
+
+	return result
+}
+
+// Reports a usage error, pointing at the help of the command, and exits.
+func usageError(message string, command string) {
+	fmt.Fprintf(os.Stderr, "%s\n", message)
+	if command != "" {
+		fmt.Fprintf(os.Stderr, "See `zig-toolchain %s --help`.\n", command)
+	} else {
+		fmt.Fprintf(os.Stderr, "See `zig-toolchain help`.\n")
+	}
+	os.Exit(ExitUsage)
+}
+
+func printFlags(flags []FlagSpec) {
+	for _, flag := range flags {
+		name := "--" + flag.Name
+		if flag.Value != "" {
+			name += " " + flag.Value
+		}
+		fmt.Printf("\n    %-24s %s", name, flag.Description)
+	}
+}
+
+func printUsageAndExit() {
+	fmt.Printf("USAGE: zig-toolchain [COMMAND] [FLAGS]\n")
+	fmt.Printf("       zig-toolchain --version\n\n")
+	fmt.Printf("COMMANDS:")
+	for _, command := range commands {
+		fmt.Printf("\n    %-16s %s", command.Name, command.Description)
+	}
+	fmt.Printf("\n\nGLOBAL FLAGS:")
+	printFlags(globalFlags)
+	fmt.Printf("\n\nRun `zig-toolchain [COMMAND] --help` for the usage of a command.\n\n")
+	os.Exit(0)
+}
+
+func (spec *CommandSpec) printUsageAndExit() {
+	for i, usage := range spec.Usage {
+		if i == 0 {
+			fmt.Printf("USAGE: zig-toolchain %s\n", usage)
+		} else {
+			fmt.Printf("       zig-toolchain %s\n", usage)
+		}
+	}
+	fmt.Printf("\n%s\n", spec.Description)
+	if len(spec.Aliases) > 0 {
+		fmt.Printf("\nALIASES: %s\n", strings.Join(spec.Aliases, ", "))
+	}
+	if len(spec.Flags) > 0 {
+		fmt.Printf("\nFLAGS:")
+		printFlags(spec.Flags)
+		fmt.Printf("\n")
+	}
+	fmt.Printf("\nRun `zig-toolchain help` for the global flags.\n\n")
+	os.Exit(0)
+}
+
+// Prints the usage of the command named by args, or of zig-toolchain.
+func commandHelp(args []string) {
+	if len(args) == 0 {
+		printUsageAndExit()
+	}
+
+	spec, ok := findCommand(args[0])
+	if !ok {
+		usageError(fmt.Sprintf("Unknown command %s.", args[0]), "")
+	}
+	spec.printUsageAndExit()
+}
@@ -0,0 +1,24 @@
+package main
+
+import "github.com/fatih/color"
+
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+func isValidColorMode(mode string) bool {
+	return mode == ColorAuto || mode == ColorAlways || mode == ColorNever
+}
+
+// Turns colors on or off. With auto, the color package already leaves them
+// off when stdout is not a terminal or NO_COLOR is set.
+func applyColorMode(mode string) {
+	switch mode {
+	case ColorAlways:
+		color.NoColor = false
+	case ColorNever:
+		color.NoColor = true
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Commands whose argument is any version, and the ones whose argument is a
+// local one.
+var (
+	completeAnyVersion   = []string{"download", "activate", "default", "pin", "why", "smoke-test", "info", "resolve", "exec", "shell", "env"}
+	completeLocalVersion = []string{"remove", "uninstall"}
+)
+
+// Prints the completion script for shell, which completes the commands,
+// their flags and, through `completion --versions`, version names.
+func commandCompletion(shell string) {
+	switch shell {
+	case "bash":
+		fmt.Print(bashCompletion())
+	case "zsh":
+		fmt.Print(zshCompletion())
+	case "fish":
+		fmt.Print(fishCompletion())
+	default:
+		usageError(fmt.Sprintf("Unsupported shell %s, expected bash, zsh or fish.", shell), "completion")
+	}
+}
+
+// Prints the version names to complete, one per line: the versions from the
+// cached indexes, the local ones, the channels and the aliases, or with
+// localOnly the local versions only. The network is never used, to keep
+// completion fast.
+func (app *AppState) commandCompleteVersions(localOnly bool) {
+	if !localOnly {
+		app.loadItems(app.cachedIndexes())
+	}
+
+	names := map[string]bool{}
+	for i := 0; i < len(app.Items); i++ {
+		item := &app.Items[i]
+		if item.Downloaded || item.Custom || !localOnly {
+			names[item.Name()] = true
+		}
+		if !localOnly {
+			for _, nomination := range item.Nominations {
+				names[nomination] = true
+			}
+		}
+	}
+	if !localOnly {
+		for _, name := range []string{ChannelStable, ChannelMaster, ChannelMach, AliasLatest} {
+			names[name] = true
+		}
+		for name := range app.userAliases() {
+			names[name] = true
+		}
+	}
+
+	result := []string{}
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+
+	for _, name := range result {
+		fmt.Printf("%s\n", name)
+	}
+}
+
+// Returns the cached index of every source, however old.
+func (app *AppState) cachedIndexes() []SourceIndex {
+	if app.IndexFile != "" {
+		if index, err := loadIndexFile(app.IndexFile); err == nil {
+			return []SourceIndex{{Source: IndexSource{Url: app.IndexFile}, Index: index}}
+		}
+		return nil
+	}
+
+	result := []SourceIndex{}
+	for _, source := range app.indexSources() {
+		if index, _, ok := readCachedIndex(source.Url); ok {
+			result = append(result, SourceIndex{Source: source, Index: index})
+		}
+	}
+
+	return result
+}
+
+func commandNames() []string {
+	result := []string{}
+	for _, command := range commands {
+		result = append(result, command.Name)
+		result = append(result, command.Aliases...)
+	}
+
+	return append(result, "help")
+}
+
+func flagNames(flags []FlagSpec) []string {
+	result := []string{}
+	for _, flag := range flags {
+		result = append(result, "--"+flag.Name)
+	}
+
+	return result
+}
+
+func bashCompletion() string {
+	var b strings.Builder
+
+	b.WriteString("# bash completion for zig-toolchain\n\n")
+	b.WriteString("_zig_toolchain() {\n")
+	b.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	b.WriteString("    if [ \"$COMP_CWORD\" -eq 1 ]; then\n")
+	fmt.Fprintf(&b, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(commandNames(), " "))
+	b.WriteString("        return\n")
+	b.WriteString("    fi\n\n")
+	b.WriteString("    local cmd=\"${COMP_WORDS[1]}\"\n")
+	b.WriteString("    if [[ \"$cur\" == --* ]]; then\n")
+	b.WriteString("        local flags=\"\"\n")
+	b.WriteString("        case \"$cmd\" in\n")
+	for _, command := range commands {
+		if len(command.Flags) > 0 {
+			names := append([]string{command.Name}, command.Aliases...)
+			fmt.Fprintf(&b, "            %s) flags=%q ;;\n", strings.Join(names, "|"), strings.Join(flagNames(command.Flags), " "))
+		}
+	}
+	b.WriteString("        esac\n")
+	fmt.Fprintf(&b, "        COMPREPLY=($(compgen -W \"$flags %s\" -- \"$cur\"))\n", strings.Join(flagNames(globalFlags), " "))
+	b.WriteString("        return\n")
+	b.WriteString("    fi\n\n")
+	b.WriteString("    case \"$cmd\" in\n")
+	fmt.Fprintf(&b, "        %s) COMPREPLY=($(compgen -W \"$(zig-toolchain completion --versions all 2>/dev/null)\" -- \"$cur\")) ;;\n", strings.Join(completeAnyVersion, "|"))
+	fmt.Fprintf(&b, "        %s) COMPREPLY=($(compgen -W \"$(zig-toolchain completion --versions local 2>/dev/null)\" -- \"$cur\")) ;;\n", strings.Join(completeLocalVersion, "|"))
+	fmt.Fprintf(&b, "        upgrade) COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", strings.Join([]string{ChannelStable, ChannelMaster, ChannelMach}, " "))
+	fmt.Fprintf(&b, "        help) COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", strings.Join(commandNames(), " "))
+	b.WriteString("        completion) COMPREPLY=($(compgen -W \"bash zsh fish\" -- \"$cur\")) ;;\n")
+	b.WriteString("        *) COMPREPLY=($(compgen -f -- \"$cur\")) ;;\n")
+	b.WriteString("    esac\n")
+	b.WriteString("}\n\n")
+	b.WriteString("complete -F _zig_toolchain zig-toolchain\n")
+
+	return b.String()
+}
+
+// Quotes a name and its description as a _describe entry, escaping the
+// colons that would be taken as the separator.
+func zshDescribe(name string, description string) string {
+	s := strings.ReplaceAll(name, ":", "\\:") + ":" + strings.ReplaceAll(description, ":", "\\:")
+	return "'" + strings.ReplaceAll(s, "'", "'\\''") + "'"
+}
+
+func zshCompletion() string {
+	var b strings.Builder
+
+	b.WriteString("#compdef zig-toolchain\n\n")
+	b.WriteString("_zig_toolchain() {\n")
+	b.WriteString("    local -a commands\n")
+	b.WriteString("    commands=(\n")
+	for _, command := range commands {
+		for _, name := range append([]string{command.Name}, command.Aliases...) {
+			fmt.Fprintf(&b, "        %s\n", zshDescribe(name, command.Description))
+		}
+	}
+	fmt.Fprintf(&b, "        %s\n", zshDescribe("help", "Show the usage of a command."))
+	b.WriteString("    )\n\n")
+	b.WriteString("    if (( CURRENT == 2 )); then\n")
+	b.WriteString("        _describe 'command' commands\n")
+	b.WriteString("        return\n")
+	b.WriteString("    fi\n\n")
+	b.WriteString("    local cmd=${words[2]}\n")
+	b.WriteString("    if [[ ${words[CURRENT]} == --* ]]; then\n")
+	b.WriteString("        local -a flags\n")
+	b.WriteString("        case $cmd in\n")
+	for _, command := range commands {
+		if len(command.Flags) > 0 {
+			names := append([]string{command.Name}, command.Aliases...)
+			fmt.Fprintf(&b, "            %s) flags=(%s) ;;\n", strings.Join(names, "|"), strings.Join(flagNames(command.Flags), " "))
+		}
+	}
+	b.WriteString("        esac\n")
+	fmt.Fprintf(&b, "        compadd -- $flags %s\n", strings.Join(flagNames(globalFlags), " "))
+	b.WriteString("        return\n")
+	b.WriteString("    fi\n\n")
+	b.WriteString("    case $cmd in\n")
+	fmt.Fprintf(&b, "        %s) compadd -- ${(f)\"$(zig-toolchain completion --versions all 2>/dev/null)\"} ;;\n", strings.Join(completeAnyVersion, "|"))
+	fmt.Fprintf(&b, "        %s) compadd -- ${(f)\"$(zig-toolchain completion --versions local 2>/dev/null)\"} ;;\n", strings.Join(completeLocalVersion, "|"))
+	fmt.Fprintf(&b, "        upgrade) compadd -- %s ;;\n", strings.Join([]string{ChannelStable, ChannelMaster, ChannelMach}, " "))
+	b.WriteString("        help) _describe 'command' commands ;;\n")
+	b.WriteString("        completion) compadd -- bash zsh fish ;;\n")
+	b.WriteString("        *) _files ;;\n")
+	b.WriteString("    esac\n")
+	b.WriteString("}\n\n")
+	b.WriteString("if [ \"$funcstack[1]\" = \"_zig_toolchain\" ]; then\n")
+	b.WriteString("    _zig_toolchain \"$@\"\n")
+	b.WriteString("else\n")
+	b.WriteString("    compdef _zig_toolchain zig-toolchain\n")
+	b.WriteString("fi\n")
+
+	return b.String()
+}
+
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	return "'" + strings.ReplaceAll(s, "'", "\\'") + "'"
+}
+
+func fishFlags(b *strings.Builder, condition string, flags []FlagSpec) {
+	for _, flag := range flags {
+		fmt.Fprintf(b, "complete -c zig-toolchain")
+		if condition != "" {
+			fmt.Fprintf(b, " -n %s", fishQuote(condition))
+		}
+		fmt.Fprintf(b, " -l %s", flag.Name)
+		if flag.Value != "" {
+			b.WriteString(" -r")
+		}
+		fmt.Fprintf(b, " -d %s\n", fishQuote(flag.Description))
+	}
+}
+
+func fishCompletion() string {
+	var b strings.Builder
+
+	b.WriteString("# fish completion for zig-toolchain\n\n")
+	b.WriteString("complete -c zig-toolchain -f\n\n")
+	for _, command := range commands {
+		for _, name := range append([]string{command.Name}, command.Aliases...) {
+			fmt.Fprintf(&b, "complete -c zig-toolchain -n __fish_use_subcommand -a %s -d %s\n", name, fishQuote(command.Description))
+		}
+	}
+	b.WriteString("complete -c zig-toolchain -n __fish_use_subcommand -a help -d 'Show the usage of a command.'\n\n")
+
+	fishFlags(&b, "", globalFlags)
+	b.WriteString("\n")
+	for _, command := range commands {
+		names := append([]string{command.Name}, command.Aliases...)
+		fishFlags(&b, "__fish_seen_subcommand_from "+strings.Join(names, " "), command.Flags)
+	}
+	b.WriteString("\n")
+
+	fmt.Fprintf(&b, "complete -c zig-toolchain -n '__fish_seen_subcommand_from %s' -a '(zig-toolchain completion --versions all 2>/dev/null)'\n", strings.Join(completeAnyVersion, " "))
+	fmt.Fprintf(&b, "complete -c zig-toolchain -n '__fish_seen_subcommand_from %s' -a '(zig-toolchain completion --versions local 2>/dev/null)'\n", strings.Join(completeLocalVersion, " "))
+	fmt.Fprintf(&b, "complete -c zig-toolchain -n '__fish_seen_subcommand_from upgrade' -a '%s'\n", strings.Join([]string{ChannelStable, ChannelMaster, ChannelMach}, " "))
+	fmt.Fprintf(&b, "complete -c zig-toolchain -n '__fish_seen_subcommand_from help' -a '%s'\n", strings.Join(commandNames(), " "))
+	b.WriteString("complete -c zig-toolchain -n '__fish_seen_subcommand_from completion' -a 'bash zsh fish'\n")
+	b.WriteString("complete -c zig-toolchain -n '__fish_seen_subcommand_from link adopt install' -F\n")
+
+	return b.String()
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// Rewrite rule applied to tarball URLs coming from the index. A trailing `*`
+// in From matches any suffix, which is then appended to To (with its own
+// trailing `*` removed), e.g.:
+//
+//	https://ziglang.org/download/* -> https://artifactory.corp/zig/*
+type RewriteRule struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+func (r RewriteRule) apply(url string) (string, bool) {
+	if strings.HasSuffix(r.From, "*") {
+		prefix := strings.TrimSuffix(r.From, "*")
+		if !strings.HasPrefix(url, prefix) {
+			return url, false
+		}
+		return strings.TrimSuffix(r.To, "*") + strings.TrimPrefix(url, prefix), true
+	}
+
+	if url == r.From {
+		return r.To, true
+	}
+
+	return url, false
+}
+
+type Config struct {
+	RewriteRules []RewriteRule `json:"rewrite_rules"`
+	// Base URLs of mirrors hosting the tarballs under their original file
+	// names, tried in order before the URL from the index.
+	Mirrors []string `json:"mirrors"`
+
+	Notify      bool   `json:"notify"`
+	LinkMode    string `json:"link_mode"`
+	AutoInstall bool   `json:"auto_install"`
+
+	// Whether to color the output: auto, always or never.
+	Color string `json:"color"`
+
+	// Point the zig symlink at ~/.zig-toolchain/active/zig instead of the
+	// versioned directory.
+	LinkViaActive bool `json:"link_via_active"`
+
+	// Extract tarballs while downloading them when activating.
+	StreamExtract bool `json:"stream_extract"`
+
+	// Keep the macOS quarantine attribute on extracted toolchains.
+	KeepQuarantine bool `json:"keep_quarantine"`
+
+	// Hardlink identical files across extracted versions to a
+	// content-addressed store.
+	Dedupe bool `json:"dedupe"`
+
+	// Download a newer master in the background when running `list`.
+	PrefetchMaster bool `json:"prefetch_master"`
+
+	// Either `git`, `keychain`, or a git-credential style helper command
+	// used to obtain credentials for tarball downloads.
+	CredentialHelper string `json:"credential_helper"`
+	// Authorization header to send to each host, e.g. a private mirror.
+	Authorization map[string]string `json:"authorization"`
+
+	// Number of attempts for network operations, and the delay in
+	// milliseconds before the first retry, which doubles on every retry.
+	Retries    int `json:"retries"`
+	RetryDelay int `json:"retry_delay_ms"`
+
+	// Number of parallel connections used to download a tarball when the
+	// server supports ranged requests.
+	Concurrency int `json:"concurrency"`
+
+	// Deadline for the whole invocation, and the longest an HTTP request may
+	// stall without receiving any data.
+	Timeout        Duration `json:"timeout"`
+	RequestTimeout Duration `json:"request_timeout"`
+
+	// How long the cached release index is used before fetching it again.
+	IndexTtl Duration `json:"index_ttl"`
+
+	// URL of the release index, e.g. of a mirror, replacing ziglang.org's.
+	IndexUrl string `json:"index_url"`
+	// Additional indexes, whose versions are merged with the release
+	// index's.
+	IndexSources []IndexSource `json:"index_sources"`
+	// Merge the index of the versions nominated by Mach, so that they can be
+	// used by their nomination, e.g. 2024.10.0-mach.
+	MachIndex bool `json:"mach_index"`
+
+	Network NetworkConfig `json:"network"`
+	Policy  Policy        `json:"policy"`
+	Prune   PruneConfig   `json:"prune"`
+}
+
+func configPath() string {
+	return localDirPath("config.json")
+}
+
+func NewConfig() *Config {
+	return &Config{
+		RewriteRules:   []RewriteRule{},
+		Mirrors:        []string{},
+		IndexSources:   []IndexSource{},
+		MachIndex:      true,
+		LinkMode:       defaultLinkMode(),
+		AutoInstall:    true,
+		Color:          ColorAuto,
+		Retries:        DefaultRetries,
+		RetryDelay:     DefaultRetryDelay,
+		Concurrency:    1,
+		IndexTtl:       Duration{DefaultIndexTtl},
+		RequestTimeout: Duration{DefaultRequestTimeout},
+		Prune:          NewPruneConfig(),
+	}
+}
+
+// Loads the config file at ~/.zig-toolchain/config.json. A missing file is
+// not an error and results in the default config.
+func LoadConfig() (*Config, error) {
+	config := NewConfig()
+
+	data, err := os.ReadFile(configPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return config, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, config)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", configPath(), err)
+	}
+
+	if !isValidLinkMode(config.LinkMode) {
+		return nil, fmt.Errorf("%s: invalid link_mode %q", configPath(), config.LinkMode)
+	}
+
+	if !isValidColorMode(config.Color) {
+		return nil, fmt.Errorf("%s: invalid color %q", configPath(), config.Color)
+	}
+
+	if err = config.Network.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", configPath(), err)
+	}
+
+	for _, source := range config.IndexSources {
+		if source.Url == "" {
+			return nil, fmt.Errorf("%s: index source %q has no url", configPath(), source.Label)
+		}
+	}
+
+	if err = config.Policy.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", configPath(), err)
+	}
+
+	return config, nil
+}
+
+// Applies the first matching rewrite rule to url.
+func (c *Config) rewriteUrl(url string) string {
+	for _, rule := range c.RewriteRules {
+		if rewritten, ok := rule.apply(url); ok {
+			logger.debugf("Rewrote %s to %s\n", url, rewritten)
+			return rewritten
+		}
+	}
+
+	return url
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// How long an HTTP request may stall by default before it is aborted.
+const DefaultRequestTimeout = time.Minute
+
+// Sets up the context of the whole invocation, which is cancelled on Ctrl-C
+// and after the overall timeout, if any.
+func (app *AppState) setupContext(timeout time.Duration) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	app.ctx, app.cancel = ctx, stop
+
+	if timeout > 0 {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		app.ctx, app.cancel = ctx, func() {
+			cancel()
+			stop()
+		}
+	}
+}
+
+func (app *AppState) context() context.Context {
+	if app.ctx == nil {
+		return context.Background()
+	}
+
+	return app.ctx
+}
+
+// Returns a context for a single HTTP request, which is cancelled if the
+// request stalls for longer than the configured request timeout. Every read
+// from the body returned by watchBody resets the timer. A request timeout of
+// zero disables it.
+func (app *AppState) requestContext() (context.Context, func(io.ReadCloser) io.ReadCloser, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(app.context())
+	timeout := app.Config.RequestTimeout.Duration
+	if timeout <= 0 {
+		return ctx, func(body io.ReadCloser) io.ReadCloser { return body }, cancel
+	}
+
+	timer := time.AfterFunc(timeout, cancel)
+	watchBody := func(body io.ReadCloser) io.ReadCloser {
+		timer.Reset(timeout)
+		return &stallReader{body: body, timer: timer, timeout: timeout}
+	}
+
+	return ctx, watchBody, func() {
+		timer.Stop()
+		cancel()
+	}
+}
+
+type stallReader struct {
+	body    io.ReadCloser
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (r *stallReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	if n > 0 {
+		r.timer.Reset(r.timeout)
+	}
+	return n, err
+}
+
+func (r *stallReader) Close() error {
+	return r.body.Close()
+}
+
+// Duration that is read from JSON as a string like "30s" or "5m".
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	d.Duration = parsed
+	return nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+const (
+	OfficialHost = "ziglang.org"
+
+	// Use `git credential fill`, i.e. whatever credential helpers git is
+	// configured with.
+	CredentialHelperGit = "git"
+	// Use the OS keychain: the macOS keychain or the freedesktop secret
+	// service (via secret-tool) on linux.
+	CredentialHelperKeychain = "keychain"
+)
+
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Runs a git-credential style helper: the request is written to its stdin
+// as key=value lines and the response is read back in the same format.
+func runCredentialHelper(cmd *exec.Cmd, u *url.URL) (*Credentials, bool) {
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("protocol=%s\nhost=%s\n\n", u.Scheme, u.Host))
+	cmd.Stderr = nil
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, false
+	}
+
+	creds := &Credentials{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		sp := strings.SplitN(scanner.Text(), "=", 2)
+		if len(sp) != 2 {
+			continue
+		}
+		switch sp[0] {
+		case "username":
+			creds.Username = sp[1]
+		case "password":
+			creds.Password = sp[1]
+		}
+	}
+
+	return creds, creds.Password != ""
+}
+
+// Looks up a password stored for the host in the OS keychain.
+func keychainCredentials(u *url.URL) (*Credentials, bool) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-internet-password", "-s", u.Hostname(), "-w")
+	case "linux":
+		cmd = exec.Command("secret-tool", "lookup", "service", "zig-toolchain", "host", u.Hostname())
+	default:
+		return nil, false
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, false
+	}
+
+	password := strings.TrimSpace(string(out))
+	return &Credentials{Password: password}, password != ""
+}
+
+// Obtains credentials for the host of rawUrl from the configured credential
+// helper. Results are cached per host for the lifetime of the process.
+func (app *AppState) credentialsFor(rawUrl string) (*Credentials, bool) {
+	helper := app.Config.CredentialHelper
+	if helper == "" {
+		return nil, false
+	}
+
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, false
+	}
+
+	app.credentialMutex.Lock()
+	defer app.credentialMutex.Unlock()
+
+	if app.credentialCache == nil {
+		app.credentialCache = map[string]*Credentials{}
+	}
+	if creds, ok := app.credentialCache[u.Host]; ok {
+		return creds, creds != nil
+	}
+
+	var creds *Credentials
+	var ok bool
+	switch helper {
+	case CredentialHelperGit:
+		creds, ok = runCredentialHelper(exec.Command("git", "credential", "fill"), u)
+	case CredentialHelperKeychain:
+		creds, ok = keychainCredentials(u)
+	default:
+		sp := strings.Fields(helper)
+		creds, ok = runCredentialHelper(exec.Command(sp[0], append(sp[1:], "get")...), u)
+	}
+
+	if !ok {
+		creds = nil
+	}
+	app.credentialCache[u.Host] = creds
+
+	return creds, ok
+}
+
+// Reports whether host is one the config points at: the host of the index
+// URL, of an index source, of a mirror or of a rewrite rule's target.
+// Credentials that aren't tied to a host are only sent to those.
+func (app *AppState) isConfiguredHost(host string) bool {
+	urls := append([]string{app.Config.IndexUrl}, app.Config.Mirrors...)
+	for _, source := range app.Config.IndexSources {
+		urls = append(urls, source.Url)
+	}
+	for _, rule := range app.Config.RewriteRules {
+		urls = append(urls, rule.To)
+	}
+
+	for _, raw := range urls {
+		if u, err := url.Parse(raw); err == nil && u.Hostname() != "" && u.Hostname() == host {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Adds the credentials for the request's host, if any, to req. In order of
+// precedence they come from an Authorization header configured for the
+// host, ZIG_TOOLCHAIN_TOKEN, ~/.netrc and the credential helper. Only the
+// Authorization headers and the netrc entries naming a host are sent to
+// hosts the config doesn't point at, e.g. the Mach index. Credentials with a
+// username use basic auth, bare passwords are sent as bearer tokens.
+func (app *AppState) authorizeRequest(req *http.Request) {
+	host := req.URL.Hostname()
+	if header, ok := app.Config.Authorization[host]; ok {
+		req.Header.Set("Authorization", header)
+		return
+	}
+
+	// The official servers never need credentials, don't leak them there.
+	if host == OfficialHost {
+		return
+	}
+
+	configured := app.isConfiguredHost(host)
+	if token := os.Getenv("ZIG_TOOLCHAIN_TOKEN"); token != "" && configured {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return
+	}
+
+	creds, ok := netrcCredentials(host, configured)
+	if !ok && configured {
+		creds, ok = app.credentialsFor(req.URL.String())
+	}
+	if !ok {
+		return
+	}
+
+	if creds.Username != "" {
+		req.SetBasicAuth(creds.Username, creds.Password)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+creds.Password)
+	}
+}
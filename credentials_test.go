@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAuthorizeRequestHostScoping(t *testing.T) {
+	netrc := filepath.Join(t.TempDir(), "netrc")
+	data := "machine named.example login user password named-secret\ndefault login anyone password default-secret\n"
+	if err := os.WriteFile(netrc, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("NETRC", netrc)
+
+	app := NewAppState()
+	app.Config.IndexUrl = "https://index.example/zig/index.json"
+	app.Config.Mirrors = []string{"https://mirror.example/zig"}
+	app.Config.RewriteRules = []RewriteRule{{From: "https://ziglang.org/download/*", To: "https://rewrite.example/zig/*"}}
+	app.Config.Authorization = map[string]string{"header.example": "Bearer header-secret", "ziglang.org": "Bearer official"}
+
+	tests := []struct {
+		url   string
+		token string
+		want  string
+	}{
+		// Configured headers win everywhere, even on ziglang.org.
+		{"https://header.example/a.tar.xz", "token", "Bearer header-secret"},
+		{"https://ziglang.org/download/index.json", "token", "Bearer official"},
+		// The token goes to the hosts the config points at only.
+		{"https://index.example/zig/index.json", "token", "Bearer token"},
+		{"https://mirror.example/zig/a.tar.xz", "token", "Bearer token"},
+		{"https://rewrite.example/zig/a.tar.xz", "token", "Bearer token"},
+		{"https://machengine.org/zig/index.json", "token", ""},
+		// So does the netrc default entry, named entries go to their host.
+		{"https://mirror.example/zig/a.tar.xz", "", basicAuth("anyone", "default-secret")},
+		{"https://machengine.org/zig/index.json", "", ""},
+		{"https://named.example/a.tar.xz", "", basicAuth("user", "named-secret")},
+	}
+
+	for _, test := range tests {
+		t.Setenv("ZIG_TOOLCHAIN_TOKEN", test.token)
+		req, err := http.NewRequest("GET", test.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		app.authorizeRequest(req)
+		if got := req.Header.Get("Authorization"); got != test.want {
+			t.Errorf("%s with token %q: Authorization = %q, want %q", test.url, test.token, got, test.want)
+		}
+	}
+}
+
+func basicAuth(username string, password string) string {
+	req, _ := http.NewRequest("GET", "https://example.com", nil)
+	req.SetBasicAuth(username, password)
+	return req.Header.Get("Authorization")
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Prints the name of the active version and nothing else, for shell prompts
+// and scripts. Only the local state is read, without building the items, so
+// that it stays cheap. Exits with 1 if no version is active.
+func (app *AppState) commandCurrent() {
+	item, ok := app.activeLocalItem()
+	if !ok {
+		fmt.Fprintf(os.Stderr, "No active version.\n")
+		os.Exit(1)
+	}
+
+	if app.Json {
+		app.printJson(app.itemJson(item))
+	} else if app.Format != nil {
+		app.printFormatted(app.itemJson(item))
+	} else {
+		fmt.Printf("%s\n", item.Name())
+	}
+}
+
+// Returns the active version from the marker and state files alone.
+func (app *AppState) activeLocalItem() (*Item, bool) {
+	if data, err := os.ReadFile(currentCustomToolchainPath()); err == nil {
+		registry, err := LoadToolchainRegistry()
+		if err != nil {
+			app.fail(err)
+		}
+		name := strings.TrimSpace(string(data))
+		dir, ok := registry.Toolchains[name]
+		if !ok {
+			return nil, false
+		}
+
+		item := &Item{Custom: true, CustomName: name, LocalPath: dir, Current: true}
+		// Only structured output shows the version, which takes running zig.
+		if app.structuredOutput() {
+			if version, err := queryZigVersion(dir); err == nil {
+				item.Version = *version
+			}
+		}
+		return item, true
+	}
+
+	state := app.loadLocalState()
+	v, ok := state.Versions[state.Active]
+	if state.Active == "" || !ok {
+		return nil, false
+	}
+	version, err := ParseVersion(v.Version)
+	if err != nil {
+		return nil, false
+	}
+
+	return &Item{
+		Version:    *version,
+		Downloaded: true,
+		Current:    true,
+		LocalPath:  v.Tarball,
+		Size:       v.Size,
+		Shasum:     v.Shasum,
+	}, true
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+type DiskSpaceError struct {
+	Path      string
+	Needed    int64
+	Available int64
+}
+
+func (e *DiskSpaceError) Error() string {
+	return fmt.Sprintf("not enough disk space in %s: need %s, only %s available", e.Path, humanSize(e.Needed), humanSize(e.Available))
+}
+
+// Checks that the file system holding dir has at least needed bytes free.
+// Passes when the free space can't be determined.
+func checkDiskSpace(dir string, needed int64) error {
+	if needed <= 0 {
+		return nil
+	}
+
+	available, err := freeDiskSpace(dir)
+	if err != nil {
+		return nil
+	}
+
+	if available < needed {
+		return &DiskSpaceError{Path: dir, Needed: needed, Available: available}
+	}
+
+	return nil
+}
+
+// Estimated space needed to extract item, from the size of its tarball.
+func (item *Item) extractedSizeEstimate() int64 {
+	size := item.Size
+	if info, err := os.Stat(item.LocalPath); err == nil && item.Downloaded {
+		size = info.Size()
+	}
+
+	return size * ExtractedSizeRatio
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+)
+
+// Prints what activating item would download, extract and link, without
+// touching the disk.
+func (app *AppState) printActivationPlan(item *Item) {
+	dir := item.LocalPath
+	if !item.Custom {
+		dir = extractedDirForItem(item)
+		if !isExtracted(dir) {
+			if !item.Downloaded {
+				fmt.Printf("Would download %s to %s (%s)\n", item.RemoteUrl, item.LocalPath, humanSize(item.Size))
+			}
+			fmt.Printf("Would extract %s to %s (~%s)\n", path.Base(item.LocalPath), dir, humanSize(item.extractedSizeEstimate()))
+		}
+	}
+
+	source := app.linkSource(dir)
+	zig := path.Join(source, zigExeName())
+	switch app.LinkMode {
+	case LinkModeSymlink:
+		fmt.Printf("Would symlink %s to %s\n", zigBinPath(), zig)
+	case LinkModeShim:
+		fmt.Printf("Would write a shim at %s running %s\n", zigShimPath(), zig)
+	case LinkModeAuto:
+		fmt.Printf("Would write a shim at %s running the zig of the current project, or %s\n", zigShimPath(), zig)
+	default:
+		// Tarballs not extracted yet will have lib/ next to zig.
+		lib := path.Join(source, "lib")
+		if item.Custom || isExtracted(dir) {
+			lib = toolchainLibDir(source)
+		}
+		fmt.Printf("Would %s %s to %s\n", app.LinkMode, zig, zigBinPath())
+		fmt.Printf("Would %s %s to %s\n", app.LinkMode, lib, zigLibLinkPath())
+	}
+
+	if previous, ok := app.GetCurrentActiveItem(); ok {
+		fmt.Printf("Would replace zig %s\n", previous.Name())
+	}
+
+	for _, name := range legacyCurrentDirs() {
+		dir := localDirPath("current", name)
+		fmt.Printf("Would delete %s (%s), left by an older release\n", dir, humanSize(dirSize(dir)))
+	}
+
+	if app.DeleteTarball && !item.Custom {
+		fmt.Printf("Would delete %s (%s)\n", item.LocalPath, humanSize(fileSize(item.LocalPath)))
+	}
+}
+
+// Prints what removing item would delete, and returns how many bytes that
+// would free.
+func (app *AppState) printRemovalPlan(item *Item) int64 {
+	freed := int64(0)
+	if size := fileSize(item.LocalPath); size > 0 {
+		fmt.Printf("Would delete %s (%s)\n", item.LocalPath, humanSize(size))
+		freed += size
+	}
+	if dir := extractedDirForItem(item); isExtracted(dir) {
+		size := dirSize(dir)
+		fmt.Printf("Would delete %s (%s)\n", dir, humanSize(size))
+		freed += size
+	}
+	if item.Current {
+		fmt.Printf("Would remove %s, deactivating zig %s\n", zigBinPath(), item.Name())
+	}
+
+	return freed
+}
+
+func fileSize(p string) int64 {
+	info, err := os.Stat(p)
+	if err != nil {
+		return 0
+	}
+
+	return info.Size()
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// Environment variable forcing a version, e.g. in CI jobs, without writing
+// files or changing the global default.
+const versionEnv = "ZIG_TOOLCHAIN_VERSION"
+
+// Places the version to use can come from, in order of precedence.
+const (
+	// The version given to exec or shell.
+	VersionSourceCommandLine = "command line"
+	// The ZIG_TOOLCHAIN_VERSION environment variable.
+	VersionSourceEnv = "environment"
+	// The .zig-version (or .tool-versions, mise.toml) of the project in the
+	// working directory.
+	VersionSourceProject = "project file"
+	// The version set with default or activate.
+	VersionSourceDefault = "global default"
+)
+
+// A version asked for by one of the sources, with where it was found. Pin
+// is empty if the source doesn't ask for any.
+type VersionChoice struct {
+	Source string
+	Pin    string
+	Origin string
+}
+
+// Returns what each source asks for, in order of precedence. cliVersion is
+// the version given on the command line, if any.
+func (app *AppState) versionChoices(cliVersion string) []VersionChoice {
+	choices := []VersionChoice{
+		{Source: VersionSourceCommandLine, Pin: cliVersion},
+		{Source: VersionSourceEnv, Pin: strings.TrimSpace(os.Getenv(versionEnv)), Origin: versionEnv},
+	}
+
+	project := VersionChoice{Source: VersionSourceProject}
+	if pin, file, ok := findProjectPin(); ok {
+		project.Pin = pin
+		project.Origin = file
+	}
+	choices = append(choices, project)
+
+	global := VersionChoice{Source: VersionSourceDefault}
+	if item, ok := app.GetCurrentActiveItem(); ok {
+		global.Pin = item.Name()
+		global.Origin = statePath()
+		if item.Custom {
+			global.Origin = currentCustomToolchainPath()
+		}
+	}
+
+	return append(choices, global)
+}
+
+// Returns the version to use: the first one asked for by a source, and which
+// source that is. Projects whose pin file is used are remembered.
+func (app *AppState) effectiveItem(cliVersion string) (*Item, VersionChoice) {
+	for _, choice := range app.versionChoices(cliVersion) {
+		if choice.Pin == "" {
+			continue
+		}
+
+		item, ok := app.itemForPin(choice.Pin)
+		if !ok {
+			pinnedBy := ""
+			switch choice.Source {
+			case VersionSourceEnv:
+				pinnedBy = versionEnv
+			case VersionSourceProject:
+				pinnedBy = path.Dir(choice.Origin)
+			}
+			app.fail(&VersionNotFoundError{Name: choice.Pin, PinnedBy: pinnedBy})
+		}
+		if choice.Source == VersionSourceProject {
+			app.registerProject(path.Dir(choice.Origin))
+		}
+		return item, choice
+	}
+
+	logger.errorf("No pinned or active version!\n")
+	os.Exit(1)
+	return nil, VersionChoice{}
+}
+
+// Prints the sources of the version to use in order of precedence, what
+// each asks for, and which one wins.
+func (app *AppState) commandExplainCurrent() {
+	choices := app.versionChoices("")
+
+	rows := [][]string{{"SOURCE", "VERSION", "FROM"}}
+	var winner *VersionChoice
+	for i, choice := range choices {
+		pin := choice.Pin
+		if pin == "" {
+			pin = "-"
+		} else if winner == nil {
+			winner = &choices[i]
+		}
+		rows = append(rows, []string{choice.Source, pin, choice.Origin})
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			if len(cell) > widths[i] {
+				widths[i] = len(cell)
+			}
+		}
+	}
+	for _, row := range rows {
+		line := fmt.Sprintf("%-*s  %-*s  %s", widths[0], row[0], widths[1], row[1], row[2])
+		fmt.Printf("%s\n", strings.TrimRight(line, " "))
+	}
+	fmt.Printf("\n")
+
+	if winner == nil {
+		fmt.Printf("No version is pinned or active, so zig isn't managed here.\n")
+		return
+	}
+
+	item, ok := app.itemForPin(winner.Pin)
+	if !ok {
+		fmt.Printf("The %s asks for %s, which isn't installed.\n", winner.Source, winner.Pin)
+		return
+	}
+	fmt.Printf("zig %s is used, from the %s.\n", item.Name(), winner.Source)
+}
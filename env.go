@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Shells the environment can be printed for. sh stands for any POSIX shell.
+var envShells = []string{"bash", "zsh", "sh", "fish", "powershell", "cmd"}
+
+func isEnvShell(shell string) bool {
+	for _, s := range envShells {
+		if s == shell {
+			return true
+		}
+	}
+
+	return false
+}
+
+// An environment variable to set. Prepended variables, i.e. PATH, keep their
+// previous value after the new one.
+type EnvVar struct {
+	Name    string
+	Value   string
+	Prepend bool
+}
+
+// Returns the environment variables that make item, extracted at dir, the
+// toolchain used: PATH with dir first, ZIG with the path of its zig binary,
+// and ZIG_TOOLCHAIN_VERSION so that nested shims and exec use it too.
+func toolchainVars(item *Item, dir string) []EnvVar {
+	return []EnvVar{
+		{Name: "PATH", Value: filepath.FromSlash(dir), Prepend: true},
+		{Name: "ZIG", Value: filepath.FromSlash(path.Join(dir, zigExeName()))},
+		{Name: versionEnv, Value: item.Name()},
+	}
+}
+
+// Returns the environment of this process with vars applied, for child
+// processes.
+func environWith(vars []EnvVar) []string {
+	env := os.Environ()
+	for _, v := range vars {
+		value := v.Value
+		if v.Prepend {
+			value += string(os.PathListSeparator) + os.Getenv(v.Name)
+		}
+		env = append(env, v.Name+"="+value)
+	}
+
+	return env
+}
+
+// Returns the name of a shell from its path, e.g. fish for /usr/bin/fish.
+func shellName(shell string) string {
+	name := strings.TrimSuffix(strings.ToLower(path.Base(filepath.ToSlash(shell))), ".exe")
+	if name == "pwsh" {
+		return "powershell"
+	}
+
+	return name
+}
+
+// Returns the shell to print the environment for when none is given: the
+// login shell, or PowerShell on Windows.
+func detectShell() string {
+	if getHostOs() == "windows" {
+		return "powershell"
+	}
+
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shellName(shell)
+	}
+
+	return "sh"
+}
+
+// Formats vars as commands setting them in shell, to be evaluated by it.
+// Unknown shells are treated as POSIX shells.
+func formatEnv(shell string, vars []EnvVar) string {
+	b := strings.Builder{}
+	for _, v := range vars {
+		switch shell {
+		case "fish":
+			if v.Prepend {
+				fmt.Fprintf(&b, "set -gx %s %s $%s;\n", v.Name, fishQuote(v.Value), v.Name)
+			} else {
+				fmt.Fprintf(&b, "set -gx %s %s;\n", v.Name, fishQuote(v.Value))
+			}
+		case "powershell":
+			value := "'" + strings.ReplaceAll(v.Value, "'", "''") + "'"
+			if v.Prepend {
+				value += " + [IO.Path]::PathSeparator + $env:" + v.Name
+			}
+			fmt.Fprintf(&b, "$env:%s = %s\n", v.Name, value)
+		case "cmd":
+			if v.Prepend {
+				fmt.Fprintf(&b, "set \"%s=%s;%%%s%%\"\n", v.Name, v.Value, v.Name)
+			} else {
+				fmt.Fprintf(&b, "set \"%s=%s\"\n", v.Name, v.Value)
+			}
+		default:
+			if v.Prepend {
+				fmt.Fprintf(&b, "export %s=%s:\"$%s\"\n", v.Name, shellQuote(v.Value), v.Name)
+			} else {
+				fmt.Fprintf(&b, "export %s=%s\n", v.Name, shellQuote(v.Value))
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// Quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Prints the environment needed to use the given version, or else the
+// active one, as commands for shell to eval. For the active version PATH
+// points at the bin directory holding the link, so that activating another
+// version later needs no new eval.
+func (app *AppState) commandEnv(version string, shell string) {
+	if shell == "" {
+		shell = detectShell()
+	}
+
+	var vars []EnvVar
+	if version != "" {
+		item, ok := app.itemForPin(version)
+		if !ok {
+			app.fail(&VersionNotFoundError{Name: version})
+		}
+
+		dir, err := app.ensureInstalled(item)
+		if err != nil {
+			app.fail(err)
+		}
+		vars = toolchainVars(item, dir)
+	} else {
+		if _, ok := app.GetCurrentActiveItem(); !ok {
+			logger.errorf("No active version!\n")
+			os.Exit(1)
+		}
+
+		zig := zigBinPath()
+		if mode := loadLinkRecord().Mode; mode == LinkModeShim || mode == LinkModeAuto {
+			zig = zigShimPath()
+		}
+		vars = []EnvVar{
+			{Name: "PATH", Value: filepath.FromSlash(path.Dir(zigBinPath())), Prepend: true},
+			{Name: "ZIG", Value: filepath.FromSlash(zig)},
+		}
+	}
+
+	fmt.Print(formatEnv(shell, vars))
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+)
+
+// Exit codes for the different kinds of failures, so that scripts can tell
+// them apart.
+const (
+	ExitFailure    = 1
+	ExitUsage      = 2
+	ExitNetwork    = 3
+	ExitChecksum   = 4
+	ExitDnsFailure = 5
+	ExitTimeout    = 6
+	ExitNotFound   = 7
+	ExitDiskSpace  = 8
+	// The version asked for is neither indexed nor local.
+	ExitVersionNotFound = 9
+	// zig has no official builds for the host.
+	ExitUnsupportedHost = 10
+	// The policy forbids the version.
+	ExitPolicy      = 11
+	ExitInterrupted = 130
+)
+
+type VersionNotFoundError struct {
+	Name string
+	// Directory whose pin file names the version, or the environment
+	// variable naming it, if any.
+	PinnedBy string
+	// How the version might be found instead, if there is a way.
+	Hint string
+}
+
+func (e *VersionNotFoundError) Error() string {
+	message := fmt.Sprintf("version %s not found", e.Name)
+	if e.PinnedBy != "" {
+		message = fmt.Sprintf("version %s pinned by %s not found", e.Name, e.PinnedBy)
+	}
+	if e.Hint != "" {
+		message += ", " + e.Hint
+	}
+	return message
+}
+
+type UnsupportedHostError struct {
+	Os   string
+	Arch string
+}
+
+func (e *UnsupportedHostError) Error() string {
+	return fmt.Sprintf("there are no official zig builds for %s/%s", e.Os, e.Arch)
+}
+
+// Returns a user facing message and an exit code for err.
+func (app *AppState) classifyError(err error) (string, int) {
+	if errors.Is(app.context().Err(), context.Canceled) {
+		return "Interrupted.", ExitInterrupted
+	}
+
+	if errors.Is(app.context().Err(), context.DeadlineExceeded) {
+		return fmt.Sprintf("Timed out after %s.", app.Config.Timeout.Duration), ExitTimeout
+	}
+
+	var notFoundErr *VersionNotFoundError
+	if errors.As(err, &notFoundErr) {
+		message := fmt.Sprintf("Version %s not found.", notFoundErr.Name)
+		if notFoundErr.PinnedBy != "" {
+			message = fmt.Sprintf("Version %s pinned by %s not found.", notFoundErr.Name, notFoundErr.PinnedBy)
+		}
+		if notFoundErr.Hint != "" {
+			message += "\n" + strings.ToUpper(notFoundErr.Hint[:1]) + notFoundErr.Hint[1:] + "."
+		}
+		return message, ExitVersionNotFound
+	}
+
+	var policyErr *PolicyError
+	if errors.As(err, &policyErr) {
+		return fmt.Sprintf("Zig %s is not allowed by policy: %s.\nPass --override-policy to ignore the policy.", policyErr.Version.String(), policyErr.Reason), ExitPolicy
+	}
+
+	var hostErr *UnsupportedHostError
+	if errors.As(err, &hostErr) {
+		return fmt.Sprintf("There are no official zig builds for %s/%s.", hostErr.Os, hostErr.Arch), ExitUnsupportedHost
+	}
+
+	var checksumErr *ChecksumError
+	if errors.As(err, &checksumErr) {
+		return fmt.Sprintf("Verification failed: %s.\nThe download may have been tampered with, or the mirror is out of date.", checksumErr), ExitChecksum
+	}
+
+	var buildErr *BuildMismatchError
+	if errors.As(err, &buildErr) {
+		return fmt.Sprintf("Verification failed: %s.", buildErr), ExitChecksum
+	}
+
+	var diskSpaceErr *DiskSpaceError
+	if errors.As(err, &diskSpaceErr) {
+		return fmt.Sprintf("Not enough disk space in %s: %s are needed, but only %s are available.", diskSpaceErr.Path, humanSize(diskSpaceErr.Needed), humanSize(diskSpaceErr.Available)), ExitDiskSpace
+	}
+
+	var statusErr *HttpStatusError
+	if errors.As(err, &statusErr) {
+		if statusErr.StatusCode == http.StatusNotFound {
+			return fmt.Sprintf("%s was not found on the server, it may have been removed.", statusErr.Url), ExitNotFound
+		}
+		return fmt.Sprintf("The server returned an error: %s.", statusErr), ExitNetwork
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return fmt.Sprintf("Could not resolve %s. Are you offline?", dnsErr.Name), ExitDnsFailure
+	}
+
+	// Request contexts are only cancelled on their own when the request
+	// stalled for longer than the request timeout.
+	var netErr net.Error
+	if errors.Is(err, context.Canceled) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return "The connection timed out.", ExitTimeout
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return fmt.Sprintf("Network error: %s.", opErr), ExitNetwork
+	}
+
+	return err.Error(), ExitFailure
+}
+
+// Prints a user facing message for err and exits with the matching exit
+// code.
+func (app *AppState) fail(err error) {
+	message, code := app.classifyError(err)
+	logger.errorf("\n%s\n", message)
+	os.Exit(code)
+}
+
+// Turns a panic into the same concise message and exit code as other
+// failures, instead of a stack trace, which is only printed with -vv.
+func (app *AppState) recoverPanic() {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	logger.tracef("%v\n%s", r, debug.Stack())
+	err, ok := r.(error)
+	if !ok {
+		err = fmt.Errorf("%v", r)
+	}
+	app.fail(err)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Walks up from the working directory looking for a project pin file.
+// Returns the pin and the file it comes from.
+func findProjectPin() (string, string, bool) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", "", false
+	}
+
+	for {
+		if pin, file, ok := readProjectPin(dir); ok {
+			return pin, file, true
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", false
+		}
+		dir = parent
+	}
+}
+
+// Returns the directory holding the zig binary for item, downloading and
+// extracting it first when needed. Missing versions are only downloaded when
+// auto-install is enabled, and tarballs downloaded earlier are verified
+// before being extracted, as when activating. Versions the policy forbids
+// aren't used even if installed.
+func (app *AppState) ensureInstalled(item *Item) (string, error) {
+	if item.Custom {
+		return item.LocalPath, nil
+	}
+	if err := app.policyError(item); err != nil {
+		return "", err
+	}
+
+	dir := extractedDirForItem(item)
+	if isExtracted(dir) {
+		return dir, nil
+	}
+
+	// Another instance may have installed it while waiting for the lock.
+	app.lockExclusively()
+	if isExtracted(dir) {
+		return dir, nil
+	}
+
+	if !item.Downloaded {
+		if !app.Config.AutoInstall {
+			return "", fmt.Errorf("zig %s is not installed and auto-install is disabled", item.Version.String())
+		}
+
+		logger.warnf("zig %s is not installed, installing...\n", item.Version.String())
+		if err := app.downloadItem(item); err != nil {
+			return "", err
+		}
+	} else if err := app.verifyTarball(item); err != nil {
+		if !app.Force {
+			return "", err
+		}
+		logger.warnf("Warning: %s, extracting anyway because of --force.\n", err)
+	}
+
+	if err := app.extractItem(item, nil); err != nil {
+		return "", err
+	}
+
+	return dir, nil
+}
+
+// Runs command with the given version, or else the toolchain pinned by the
+// current project (or the active one), first in PATH, and exits with its exit
+// code. Nothing is activated.
+func (app *AppState) commandExec(version string, command []string) {
+	item, _ := app.effectiveItem(version)
+
+	dir, err := app.ensureInstalled(item)
+	if err != nil {
+		app.fail(err)
+	}
+	recordUsage(item)
+	app.releaseLock()
+
+	cmd := exec.Command(resolveCommand(command[0], dir), command[1:]...)
+	cmd.Env = environWith(toolchainVars(item, dir))
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	} else if err != nil {
+		app.fail(err)
+	}
+}
+
+// Returns the path of name in the toolchain directory dir if it's there, so
+// the command is resolved against the new PATH rather than ours, which may
+// lead back to the auto shim. Otherwise name is returned as is.
+func resolveCommand(name string, dir string) string {
+	if strings.ContainsRune(name, os.PathSeparator) {
+		return name
+	}
+
+	candidates := []string{name}
+	if getHostOs() == "windows" && path.Ext(name) == "" {
+		candidates = append(candidates, name+".exe")
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(path.Join(dir, candidate)); err == nil {
+			return path.Join(dir, candidate)
+		}
+	}
+
+	return name
+}
@@ -3,8 +3,10 @@ module github.com/dmbfm/zig-toolchain
 go 1.19
 
 require (
-	github.com/fatih/color v1.14.1 // indirect
-	github.com/mattn/go-colorable v0.1.13 // indirect
-	github.com/mattn/go-isatty v0.0.17 // indirect
-	golang.org/x/sys v0.3.0 // indirect
+	github.com/fatih/color v1.14.1
+	github.com/mattn/go-isatty v0.0.17
+	github.com/ulikunitz/xz v0.5.17
+	golang.org/x/sys v0.3.0
 )
+
+require github.com/mattn/go-colorable v0.1.13 // indirect
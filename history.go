@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+const (
+	// Number of activations remembered for rollback.
+	MaxActivations = 10
+)
+
+// Usage history of the local versions.
+type History struct {
+	// When each version was last used, keyed by its tarball base name.
+	LastUsed map[string]time.Time `json:"last_used"`
+	// Names of the most recently activated versions, the latest last.
+	Activations []string `json:"activations"`
+}
+
+func historyPath() string {
+	return localDirPath("history.json")
+}
+
+func LoadHistory() (*History, error) {
+	history := &History{LastUsed: map[string]time.Time{}}
+
+	data, err := os.ReadFile(historyPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return history, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, history)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", historyPath(), err)
+	}
+	if history.LastUsed == nil {
+		history.LastUsed = map[string]time.Time{}
+	}
+
+	return history, nil
+}
+
+func (h *History) Save() error {
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(historyPath(), data, 0644)
+}
+
+// Records that item was just activated or used. Failing to do so never
+// fails the command using it.
+func recordUsage(item *Item) {
+	if item.Custom {
+		return
+	}
+
+	history, err := LoadHistory()
+	if err != nil {
+		return
+	}
+
+	history.LastUsed[tarballBaseName(item.LocalPath)] = time.Now().UTC()
+	history.Save()
+}
+
+// Records that item was just activated, for rollback.
+func recordActivation(item *Item) {
+	history, err := LoadHistory()
+	if err != nil {
+		return
+	}
+
+	name := item.Name()
+	if !item.Custom {
+		name = item.Version.FullString()
+		history.LastUsed[tarballBaseName(item.LocalPath)] = time.Now().UTC()
+	}
+
+	history.Activations = append(history.Activations, name)
+	if len(history.Activations) > MaxActivations {
+		history.Activations = history.Activations[len(history.Activations)-MaxActivations:]
+	}
+	history.Save()
+}
+
+// Returns when item was last used, falling back to when it was downloaded.
+func (h *History) lastUsed(item *Item) time.Time {
+	if t, ok := h.LastUsed[tarballBaseName(item.LocalPath)]; ok {
+		return t
+	}
+
+	if info, err := os.Stat(item.LocalPath); err == nil {
+		return info.ModTime()
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+const (
+	DefaultDialTimeout = 30 * time.Second
+)
+
+// Network options for the HTTP client, for environments where the default
+// dialer behaves badly (e.g. only one IP family actually works).
+type NetworkConfig struct {
+	// Either "4" or "6" to only use that IP family. Empty uses both.
+	IpVersion string `json:"ip_version"`
+	// Dial timeout in seconds.
+	DialTimeout int `json:"dial_timeout"`
+	// Delay in milliseconds before racing a fallback connection with the
+	// other IP family ("happy eyeballs"). A negative value disables it.
+	FallbackDelay int `json:"fallback_delay_ms"`
+	// DNS server (host:port) to use instead of the system resolver.
+	DnsServer string `json:"dns_server"`
+
+	// Proxy URL. When empty, HTTP_PROXY/HTTPS_PROXY/NO_PROXY are honored.
+	Proxy string `json:"proxy"`
+	// PEM file with extra CA certificates to trust, e.g. a corporate CA.
+	CaCert string `json:"ca_cert"`
+	// Skip TLS certificate verification.
+	Insecure bool `json:"insecure"`
+}
+
+func (n *NetworkConfig) validate() error {
+	if n.IpVersion != "" && n.IpVersion != "4" && n.IpVersion != "6" {
+		return fmt.Errorf("invalid ip_version %q, expected \"4\" or \"6\"", n.IpVersion)
+	}
+
+	return nil
+}
+
+func (n *NetworkConfig) dialer() *net.Dialer {
+	dialer := &net.Dialer{
+		Timeout:       DefaultDialTimeout,
+		KeepAlive:     30 * time.Second,
+		FallbackDelay: time.Duration(n.FallbackDelay) * time.Millisecond,
+	}
+
+	if n.DialTimeout > 0 {
+		dialer.Timeout = time.Duration(n.DialTimeout) * time.Second
+	}
+
+	if n.DnsServer != "" {
+		server := n.DnsServer
+		dialer.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return (&net.Dialer{Timeout: dialer.Timeout}).DialContext(ctx, network, server)
+			},
+		}
+	}
+
+	return dialer
+}
+
+// Returns the HTTP client shared by index fetches and downloads.
+func (app *AppState) httpClient() *http.Client {
+	if app.client != nil {
+		return app.client
+	}
+
+	network := app.Config.Network
+	dialer := network.dialer()
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if network.Proxy != "" {
+		proxy, err := url.Parse(network.Proxy)
+		if err != nil {
+			app.fail(fmt.Errorf("invalid proxy URL: %w", err))
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if network.CaCert != "" || network.Insecure {
+		tlsConfig := &tls.Config{InsecureSkipVerify: network.Insecure}
+
+		if network.CaCert != "" {
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+
+			pem, err := os.ReadFile(network.CaCert)
+			if err != nil {
+				app.fail(fmt.Errorf("failed to read CA certificates: %w", err))
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				app.fail(fmt.Errorf("no valid certificates found in %s", network.CaCert))
+			}
+			tlsConfig.RootCAs = pool
+		}
+
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	transport.DialContext = func(ctx context.Context, n string, addr string) (net.Conn, error) {
+		if network.IpVersion != "" && n == "tcp" {
+			n = "tcp" + network.IpVersion
+		}
+		return dialer.DialContext(ctx, n, addr)
+	}
+
+	app.client = &http.Client{Transport: &loggingTransport{transport}}
+	return app.client
+}
+
+// Logs every request and the status code of its response with -v.
+type loggingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	logger.debugf("%s %s\n", req.Method, req.URL.Redacted())
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		logger.debugf("%s %s failed: %s\n", req.Method, req.URL.Redacted(), err)
+		return nil, err
+	}
+	logger.debugf("%s %s: %s\n", req.Method, req.URL.Redacted(), res.Status)
+
+	return res, nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	DefaultIndexTtl = time.Hour
+)
+
+// Returns the path of the cached copy of the index at url. Indexes other
+// than ziglang.org's are cached under a hash of their URL.
+func indexCachePath(url string) string {
+	return localDirPath("cache", indexCacheName(url)+".json")
+}
+
+func indexCacheName(url string) string {
+	if url == IndexUrl {
+		return "index"
+	}
+
+	sum := sha256.Sum256([]byte(url))
+	return "index-" + hex.EncodeToString(sum[:])[:12]
+}
+
+// Returns the cached index at url, if it was fetched within the configured
+// TTL.
+func (app *AppState) cachedIndex(url string) (*ZigIndex, bool) {
+	index, fetched, ok := readCachedIndex(url)
+	if !ok || time.Since(fetched) > app.Config.IndexTtl.Duration {
+		return nil, false
+	}
+
+	logger.debugf("Cache hit for %s (fetched %s)\n", url, fetched.Format("2006-01-02 15:04"))
+	return index, true
+}
+
+// Returns the cached index at url regardless of its age, and when it was
+// fetched.
+func readCachedIndex(url string) (*ZigIndex, time.Time, bool) {
+	info, err := os.Stat(indexCachePath(url))
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+
+	data, err := os.ReadFile(indexCachePath(url))
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+
+	index, err := parseIndex(data)
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+
+	return index, info.ModTime(), true
+}
+
+// HTTP validators of a cached response, sent back to only download it again
+// if it changed.
+type CacheValidators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+func indexValidatorsPath(url string) string {
+	return localDirPath("cache", indexCacheName(url)+".validators.json")
+}
+
+func validatorsFromResponse(resp *http.Response) *CacheValidators {
+	return &CacheValidators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+}
+
+func (v *CacheValidators) apply(req *http.Request) {
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+}
+
+// Loads the validators of the cached index at url, if there is a cached
+// index to fall back to.
+func loadIndexValidators(url string) *CacheValidators {
+	if _, err := os.Stat(indexCachePath(url)); err != nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(indexValidatorsPath(url))
+	if err != nil {
+		return nil
+	}
+
+	validators := &CacheValidators{}
+	if err = json.Unmarshal(data, validators); err != nil || (validators.ETag == "" && validators.LastModified == "") {
+		return nil
+	}
+
+	return validators
+}
+
+// Fetches the index at url and caches it, retrying on failure if retry is
+// set. A cached index is revalidated instead, and only downloaded again if
+// it changed.
+func (app *AppState) refreshIndex(url string, retry bool) (*ZigIndex, error) {
+	cached := loadIndexValidators(url)
+	var data []byte
+	var validators *CacheValidators
+	fetch := func() error {
+		var err error
+		data, validators, err = app.fetchIndexData(url, cached)
+		return err
+	}
+
+	var err error
+	if retry {
+		err = app.withRetry("Fetching the index", fetch)
+	} else {
+		err = fetch()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Not modified, the cached index is fresh again.
+	if data == nil {
+		now := time.Now()
+		if err = os.Chtimes(indexCachePath(url), now, now); err != nil {
+			return nil, err
+		}
+		if data, err = os.ReadFile(indexCachePath(url)); err != nil {
+			return nil, err
+		}
+		return parseIndex(data)
+	}
+
+	index, err := parseIndex(data)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp := indexCachePath(url) + ".tmp"
+	if err = os.WriteFile(tmp, data, 0644); err == nil {
+		err = os.Rename(tmp, indexCachePath(url))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	validatorData, err := json.MarshalIndent(validators, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err = os.WriteFile(indexValidatorsPath(url), validatorData, 0644); err != nil {
+		return nil, err
+	}
+
+	return index, nil
+}
+
+func (app *AppState) commandUpdate() {
+	indexed := 0
+	for i := 0; i < len(app.Items); i++ {
+		if app.Items[i].Indexed {
+			indexed++
+		}
+	}
+
+	logger.infof("Updated the release index (%d versions).\n", indexed)
+	if master, ok := app.masterItem(); ok {
+		logger.infof("Latest master: %s\n", master.Version.FullString())
+	}
+	if stable, ok := app.latestStableItem(); ok {
+		logger.infof("Latest stable: %s\n", stable.Version.FullString())
+	}
+}
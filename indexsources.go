@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// An index in the format of the ziglang.org one, e.g. of an internal fork.
+// The versions of every source are merged into a single list.
+type IndexSource struct {
+	Url   string `json:"url"`
+	Label string `json:"label"`
+	// When several sources index the same version, the one with the highest
+	// priority wins. The main index has priority 0.
+	Priority int `json:"priority"`
+}
+
+// An index along with the source it was loaded from.
+type SourceIndex struct {
+	Source IndexSource
+	Index  *ZigIndex
+}
+
+// Returns the URL of the main index: the one from the config, or
+// ziglang.org's.
+func (c *Config) indexUrl() string {
+	if c.IndexUrl != "" {
+		return c.IndexUrl
+	}
+	return IndexUrl
+}
+
+// Returns the main index and the configured sources, from the highest
+// priority to the lowest.
+func (app *AppState) indexSources() []IndexSource {
+	sources := append([]IndexSource{{Url: app.Config.indexUrl()}}, app.Config.IndexSources...)
+	if app.Config.MachIndex && !hasIndexSource(sources, MachIndexUrl) {
+		// Below the release index, which has the newer builds Mach nominates
+		// too, with the same tarballs.
+		sources = append(sources, IndexSource{Url: MachIndexUrl, Label: ChannelMach, Priority: -1})
+	}
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].Priority > sources[j].Priority
+	})
+
+	return sources
+}
+
+func hasIndexSource(sources []IndexSource, url string) bool {
+	for _, source := range sources {
+		if source.Url == url {
+			return true
+		}
+	}
+	return false
+}
+
+func (s IndexSource) description() string {
+	if s.Label != "" {
+		return s.Label
+	}
+	return s.Url
+}
+
+// Loads the index of every source. Only failing to load the main index is
+// fatal, the other sources are skipped with a warning. With an index
+// snapshot, only the snapshot is loaded.
+func (app *AppState) loadIndexes(command int) []SourceIndex {
+	if app.IndexFile != "" {
+		index, err := loadIndexFile(app.IndexFile)
+		if err != nil {
+			app.fail(err)
+		}
+		return []SourceIndex{{Source: IndexSource{Url: app.IndexFile}, Index: index}}
+	}
+
+	result := []SourceIndex{}
+	for _, source := range app.indexSources() {
+		main := source.Url == app.Config.indexUrl()
+		index, err := app.loadIndex(source, command)
+		if err != nil {
+			if main {
+				app.fail(err)
+			}
+			logger.warnf("Failed to load the index of %s: %s\n", source.description(), err)
+			continue
+		}
+		if index != nil {
+			result = append(result, SourceIndex{Source: source, Index: index})
+		}
+	}
+
+	if len(result) == 0 {
+		app.requireNetwork("fetch the release index")
+	}
+
+	return result
+}
+
+// Loads an index snapshot written by `index export`.
+func loadIndexFile(file string) (*ZigIndex, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := parseIndex(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	return index, nil
+}
+
+// Prints the merged indexes in the format of the ziglang.org one, to be
+// used later with --index-file. When several indexes have an entry for the
+// same key, the one with the highest priority is kept.
+func (app *AppState) commandIndexExport() {
+	entries := map[string]ZigIndexEntry{}
+	for _, index := range app.Indexes {
+		for key, entry := range index.Index.Entries {
+			if _, ok := entries[key]; !ok {
+				entries[key] = entry
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		app.fail(err)
+	}
+
+	fmt.Printf("%s\n", data)
+}
@@ -0,0 +1,51 @@
+package main
+
+import "fmt"
+
+// Prints everything known about the version name resolves to: when it was
+// released, where its tarball and documentation live, and where it is
+// installed.
+func (app *AppState) commandInfo(name string) {
+	item, ok := app.resolveName(name)
+	if !ok {
+		app.fail(&VersionNotFoundError{Name: name})
+	}
+
+	info := app.itemJson(item)
+	if app.Json {
+		app.printJson(info)
+		return
+	} else if app.Format != nil {
+		app.printFormatted(info)
+		return
+	}
+
+	fmt.Printf("zig %s\n", info.Name)
+	printInfoField("Version", info.Version)
+	printInfoField("Channel", info.Channel)
+	printInfoField("Released", info.Date)
+	if info.Commit != "" {
+		printInfoField("Commit", fmt.Sprintf("%s (%s)", info.Commit, info.CommitUrl))
+	}
+	printInfoField("Tarball", info.Url)
+	printInfoField("Shasum", info.Shasum)
+	if info.Size > 0 {
+		printInfoField("Size", item.sizeDescription())
+	}
+	printInfoField("Docs", info.Docs)
+	printInfoField("Std docs", info.StdDocs)
+	printInfoField("Source", info.Source)
+	printInfoField("Local", info.Tarball)
+	printInfoField("Extracted", info.Dir)
+	if info.Active {
+		printInfoField("Active", "yes")
+	} else {
+		printInfoField("Active", "no")
+	}
+}
+
+func printInfoField(name string, value string) {
+	if value != "" {
+		fmt.Printf("%-11s%s\n", name+":", value)
+	}
+}
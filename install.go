@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// Operating systems in the names of release artifacts, which tell the target
+// apart from the version in them.
+var artifactOses = []string{"linux", "macos", "windows", "freebsd", "netbsd", "openbsd", "dragonfly", "wasi"}
+
+// Parses the base name of a release artifact, e.g. `zig-linux-x86_64-0.11.0`
+// or `zig-x86_64-linux-0.14.0-dev.1234+abcdef`, into its target (the
+// architecture and the os, in the order they appear in) and its version.
+// Zig changed the order of the os and the architecture in the names before,
+// so instead of relying on positions, the version is the part that parses as
+// one, and the target the os and the architecture around it.
+func parseArtifactName(name string) ([]string, *Version, error) {
+	sp := strings.Split(name, "-")
+	if len(sp) < 4 || sp[0] != "zig" {
+		return nil, nil, fmt.Errorf("%s is not a zig release artifact", name)
+	}
+
+	for i := 1; i < len(sp); i++ {
+		if sp[i] == "" || sp[i][0] < '0' || sp[i][0] > '9' {
+			continue
+		}
+
+		// The longest version first, as prereleases contain dashes too.
+		for j := len(sp); j > i; j-- {
+			target := append(append([]string{}, sp[1:i]...), sp[j:]...)
+			if len(target) != 2 || !(isArtifactOs(target[0]) || isArtifactOs(target[1])) {
+				continue
+			}
+			if version, err := ParseVersion(strings.Join(sp[i:j], "-")); err == nil {
+				return target, version, nil
+			}
+		}
+	}
+
+	return nil, nil, fmt.Errorf("no version found in %s", name)
+}
+
+func isArtifactOs(name string) bool {
+	for _, known := range artifactOses {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Parses the version out of a release tarball's file name, e.g.
+// `zig-linux-x86_64-0.11.0.tar.xz` or `zig-x86_64-windows-0.14.1.zip`, and
+// checks that it was built for the host.
+func parseTarballName(name string) (*Version, error) {
+	if archiveExt(name) == "" {
+		return nil, fmt.Errorf("%s is not a .tar.xz or .zip archive", name)
+	}
+
+	target, version, err := parseArtifactName(tarballBaseName(name))
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a zig release tarball", name)
+	}
+
+	hostOs := getHostOs()
+	for _, hostArch := range hostArchNames() {
+		if (target[0] == hostOs && target[1] == hostArch) || (target[0] == hostArch && target[1] == hostOs) {
+			return version, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s is not built for %s", name, hostTargets()[0])
+}
+
+// Returns the version of the local tarball at tarballPath, from its name or
+// else from the name of the directory inside it, for tarballs that were
+// renamed.
+func tarballVersion(tarballPath string) (*Version, error) {
+	if _, version, err := parseArtifactName(tarballBaseName(tarballPath)); err == nil {
+		return version, nil
+	}
+
+	dir, err := archiveTopLevelDir(tarballPath)
+	if err != nil {
+		return nil, err
+	}
+
+	_, version, err := parseArtifactName(dir)
+	return version, err
+}
+
+// Checks that the tarball at tarballPath can be read and contains a zig
+// binary in its top-level directory.
+func validateTarball(tarballPath string) error {
+	entries, err := tarballEntries(tarballPath)
+	if err != nil {
+		return err
+	}
+
+	base := tarballBaseName(tarballPath)
+	hasZig := false
+	for _, entry := range entries {
+		entry = strings.TrimPrefix(entry, "./")
+		if err := checkMemberName(entry); err != nil {
+			return fmt.Errorf("%s: %w", tarballPath, err)
+		}
+		if entry != base && !strings.HasPrefix(entry, base+"/") {
+			return fmt.Errorf("%s: unexpected entry %s outside of %s/", tarballPath, entry, base)
+		}
+		if entry == base+"/zig" || entry == base+"/zig.exe" {
+			hasZig = true
+		}
+	}
+
+	if !hasZig {
+		return fmt.Errorf("%s: no zig binary found in %s/", tarballPath, base)
+	}
+
+	return nil
+}
+
+// Copies src to dst through a temporary file, so that an interrupted copy
+// never leaves a partial file at dst.
+func copyFileAtomic(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(path.Dir(dst), ".download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, in)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), dst)
+}
+
+// Installs a release tarball from the local file system, without any network
+// access, e.g. on air-gapped machines. The tarball is validated and copied
+// into ~/.zig-toolchain/tarballs, and optionally activated.
+func (app *AppState) commandInstallFile(file string, activate bool) {
+	version, err := parseTarballName(path.Base(file))
+	if err != nil {
+		app.fail(fmt.Errorf("invalid tarball: %w", err))
+	}
+
+	logger.infof("Validating %s...", path.Base(file))
+	if err = validateTarball(file); err != nil {
+		app.fail(err)
+	}
+	logger.infof("Done!\n")
+
+	localPath := localDirPath("tarballs", path.Base(file))
+	item, ok := app.GetItemByVersion(*version)
+	if ok && item.Downloaded && !app.Force {
+		logger.infof("zig %s is already installed. Pass --force to replace it.\n", version.String())
+	} else {
+		logger.infof("Copying to %s...", localPath)
+		if err = copyFileAtomic(file, localPath); err != nil {
+			app.fail(err)
+		}
+		logger.infof("Done!\n")
+
+		if !ok {
+			app.Items = append(app.Items, Item{Version: *version})
+			item = &app.Items[len(app.Items)-1]
+		}
+		item.Downloaded = true
+		item.LocalPath = localPath
+		app.recordDownloaded(item)
+	}
+
+	if activate {
+		app.commandActivateItem(item)
+	} else if app.DeleteTarball {
+		// The tarball can only go once the version is extracted.
+		logger.infof("Extracting %s...", path.Base(item.LocalPath))
+		if !isExtracted(extractedDirForItem(item)) {
+			if err = app.extractItem(item, nil); err != nil {
+				app.fail(err)
+			}
+		}
+		if _, err = app.removeExtractedTarball(item); err != nil {
+			app.fail(err)
+		}
+		logger.infof("Done!\n")
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// Stable JSON schema of an item, printed by the read commands with --json
+// and passed to --format templates. Fields are only ever added, never renamed
+// or removed.
+type ItemJson struct {
+	Name        string   `json:"name"`
+	Version     string   `json:"version"`
+	Channel     string   `json:"channel"`
+	Custom      bool     `json:"custom"`
+	Downloaded  bool     `json:"downloaded"`
+	Active      bool     `json:"active"`
+	Date        string   `json:"date,omitempty"`
+	Commit      string   `json:"commit,omitempty"`
+	CommitUrl   string   `json:"commit_url,omitempty"`
+	Size        int64    `json:"size,omitempty"`
+	Url         string   `json:"url,omitempty"`
+	Shasum      string   `json:"shasum,omitempty"`
+	Tarball     string   `json:"tarball,omitempty"`
+	Dir         string   `json:"dir,omitempty"`
+	Docs        string   `json:"docs,omitempty"`
+	StdDocs     string   `json:"std_docs,omitempty"`
+	Source      string   `json:"source,omitempty"`
+	Aliases     []string `json:"aliases"`
+	Nominations []string `json:"nominations,omitempty"`
+	Platforms   []string `json:"platforms"`
+}
+
+// Returns the channel of item: master for the master build, stable for
+// releases, dev for other dev builds and prerelease for release candidates.
+func (item *Item) channel() string {
+	switch {
+	case item.Custom:
+		return "custom"
+	case item.Master:
+		return ChannelMaster
+	case !item.Version.isPrerelease():
+		return ChannelStable
+	case item.Version.Dev:
+		return "dev"
+	}
+
+	return "prerelease"
+}
+
+func (app *AppState) itemJson(item *Item) ItemJson {
+	result := ItemJson{
+		Name:        item.Name(),
+		Version:     item.Version.FullString(),
+		Channel:     item.channel(),
+		Custom:      item.Custom,
+		Downloaded:  item.Downloaded,
+		Active:      item.Current,
+		Date:        item.Date,
+		Size:        item.Size,
+		Url:         item.RemoteUrl,
+		Shasum:      item.Shasum,
+		Docs:        item.Docs,
+		StdDocs:     item.StdDocs,
+		Source:      item.SourceLabel,
+		Aliases:     app.aliasesFor(item),
+		Nominations: item.Nominations,
+		Platforms:   item.Platforms,
+	}
+	if item.Version.Dev && item.Version.Commit != "" {
+		result.Commit = item.Version.Commit
+		result.CommitUrl = fmt.Sprintf("%s/commit/%s", ZigRepoUrl, item.Version.Commit)
+	}
+	if result.Platforms == nil {
+		result.Platforms = []string{}
+	}
+
+	if item.Custom {
+		result.Dir = item.LocalPath
+	} else if item.Downloaded {
+		if _, err := os.Stat(item.LocalPath); err == nil {
+			result.Tarball = item.LocalPath
+		}
+		if dir := extractedDirForItem(item); isExtracted(dir) {
+			result.Dir = dir
+		}
+	}
+
+	return result
+}
+
+// Reports whether items are to be printed as JSON or with a template
+// rather than as text.
+func (app *AppState) structuredOutput() bool {
+	return app.Json || app.Format != nil
+}
+
+// Prints items as a JSON array, or each on its own line with the --format
+// template.
+func (app *AppState) printItems(items []*Item) {
+	result := []ItemJson{}
+	for _, item := range items {
+		result = append(result, app.itemJson(item))
+	}
+
+	if app.Format == nil {
+		app.printJson(result)
+		return
+	}
+
+	for _, item := range result {
+		app.printFormatted(item)
+	}
+}
+
+// Parses a --format template, e.g. `{{.Version}} {{.Date}}`, which is
+// executed with the ItemJson of each item. As shells make it awkward to pass
+// tabs, `\t` stands for one.
+func parseFormat(format string) (*template.Template, error) {
+	format = strings.ReplaceAll(format, `\t`, "\t")
+	return template.New("format").Funcs(template.FuncMap{"join": strings.Join}).Parse(format)
+}
+
+func (app *AppState) printFormatted(item ItemJson) {
+	if err := app.Format.Execute(os.Stdout, item); err != nil {
+		app.fail(fmt.Errorf("invalid format: %w", err))
+	}
+	fmt.Printf("\n")
+}
+
+func (app *AppState) printJson(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		app.fail(fmt.Errorf("failed to encode JSON: %w", err))
+	}
+
+	fmt.Printf("%s\n", data)
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+const (
+	LinkModeSymlink  = "symlink"
+	LinkModeHardlink = "hardlink"
+	LinkModeCopy     = "copy"
+	LinkModeShim     = "shim"
+	// Shim that runs the version of the project in the working directory,
+	// or the active one, through `zig-toolchain exec`.
+	LinkModeAuto = "auto"
+
+	// Marker file placed in the lib directory we manage, so we never remove a
+	// lib directory that belongs to someone else.
+	libMarkerFile = ".zig-toolchain"
+)
+
+func isValidLinkMode(mode string) bool {
+	return mode == LinkModeSymlink || mode == LinkModeHardlink || mode == LinkModeCopy || mode == LinkModeShim || mode == LinkModeAuto
+}
+
+// Symlinks usually need elevated privileges on Windows, so a shim is used
+// there by default.
+func defaultLinkMode() string {
+	if getHostOs() == "windows" {
+		return LinkModeShim
+	}
+
+	return LinkModeSymlink
+}
+
+// Record of what the last activation placed outside of ~/.zig-toolchain, so
+// that the next one knows what to replace, even if the link mode changed in
+// between.
+type LinkRecord struct {
+	Mode  string   `json:"mode"`
+	Dir   string   `json:"dir"`
+	Paths []string `json:"paths"`
+}
+
+func linkRecordPath() string {
+	return localDirPath("links.json")
+}
+
+// Loads the link record, which is empty if nothing was linked yet.
+func loadLinkRecord() *LinkRecord {
+	record := &LinkRecord{}
+	if data, err := os.ReadFile(linkRecordPath()); err == nil {
+		json.Unmarshal(data, record)
+	}
+
+	return record
+}
+
+func (r *LinkRecord) save() error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(linkRecordPath(), data, 0644)
+}
+
+// Zig looks for its standard library relative to the location of the zig
+// executable, so when the binary is hardlinked or copied into the bin
+// directory, the lib directory is mirrored at <bin>/../lib/zig.
+func zigLibLinkPath() string {
+	return path.Join(path.Dir(path.Dir(zigBinPath())), "lib", "zig")
+}
+
+// Returns the lib directory of the toolchain whose zig binary is in dir:
+// lib/ next to it for tarballs, ../lib/zig for install prefixes.
+func toolchainLibDir(dir string) string {
+	if _, err := os.Stat(path.Join(dir, "lib")); err == nil {
+		return path.Join(dir, "lib")
+	}
+
+	return path.Join(path.Dir(dir), "lib", "zig")
+}
+
+// Removes the zig binary and lib directory exposed by a previous activation.
+func unlinkToolchain() error {
+	return removeLinks("")
+}
+
+// Same as unlinkToolchain, but leaves the file at keep in place, to be
+// replaced by the next activation.
+func removeLinks(keep string) error {
+	paths := append([]string{zigBinPath(), zigShimPath()}, loadLinkRecord().Paths...)
+	for _, p := range paths {
+		if p == keep || p == zigLibLinkPath() {
+			continue
+		}
+		if _, err := os.Lstat(p); err == nil {
+			if err = os.Remove(p); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := os.Stat(path.Join(zigLibLinkPath(), libMarkerFile)); err == nil {
+		if err = os.RemoveAll(zigLibLinkPath()); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Remove(linkRecordPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// Exposes the zig binary of the toolchain extracted at dir in the bin
+// directory, using the given link mode. The binary (or shim) is replaced by
+// renaming a new one over it, so zig is never missing while switching.
+func linkToolchain(dir string, mode string, force bool) error {
+	target := zigBinPath()
+	if mode == LinkModeShim || mode == LinkModeAuto {
+		target = zigShimPath()
+	}
+
+	if mode == LinkModeHardlink || mode == LinkModeCopy {
+		if err := checkLibLinkPath(force); err != nil {
+			return err
+		}
+	}
+
+	err := removeLinks(target)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(path.Dir(target), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	zig := path.Join(dir, zigExeName())
+	tmp := target + ".tmp"
+	os.Remove(tmp)
+
+	switch mode {
+	case LinkModeSymlink:
+		err = os.Symlink(zig, tmp)
+	case LinkModeShim:
+		// The shim runs zig from its own directory, so zig finds its lib
+		// directory there.
+		err = os.WriteFile(tmp, []byte(zigShimScript(zig)), 0755)
+	case LinkModeAuto:
+		var exe string
+		if exe, err = toolExecutablePath(); err == nil {
+			err = os.WriteFile(tmp, []byte(zigAutoShimScript(exe)), 0755)
+		}
+	default:
+		err = placeFile(zig, tmp, mode)
+	}
+	if err == nil {
+		err = os.Rename(tmp, target)
+	}
+	if err != nil {
+		return err
+	}
+
+	record := &LinkRecord{Mode: mode, Dir: dir, Paths: []string{target}}
+	if mode == LinkModeSymlink || mode == LinkModeShim || mode == LinkModeAuto {
+		return record.save()
+	}
+
+	libDir := toolchainLibDir(dir)
+	err = filepath.WalkDir(libDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(libDir, p)
+		if err != nil {
+			return err
+		}
+		dest := path.Join(zigLibLinkPath(), filepath.ToSlash(rel))
+
+		if d.IsDir() {
+			return os.MkdirAll(dest, os.ModePerm)
+		}
+
+		return placeFile(p, dest, mode)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(path.Join(zigLibLinkPath(), libMarkerFile), []byte{}, 0644)
+	if err != nil {
+		return err
+	}
+
+	record.Paths = append(record.Paths, zigLibLinkPath())
+	return record.save()
+}
+
+// Makes sure the lib directory mirrored by the hardlink and copy modes is
+// ours to replace. A lib directory without our marker, e.g. of a zig
+// installed by hand, is only removed with force.
+func checkLibLinkPath(force bool) error {
+	libDir := zigLibLinkPath()
+	if _, err := os.Lstat(libDir); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(path.Join(libDir, libMarkerFile)); err == nil {
+		return nil
+	}
+
+	if !force {
+		return fmt.Errorf("%s already exists and wasn't created by zig-toolchain, pass --force to replace it", libDir)
+	}
+
+	logger.warnf("Replacing %s, which wasn't created by zig-toolchain\n", libDir)
+	return os.RemoveAll(libDir)
+}
+
+// Hardlinks or copies the file at src to dest.
+func placeFile(src string, dest string, mode string) error {
+	if mode == LinkModeHardlink {
+		err := os.Link(src, dest)
+		if err != nil {
+			return fmt.Errorf("failed to hardlink (is the bin directory on another filesystem?): %w", err)
+		}
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+const (
+	SortVersion = "version"
+	SortDate    = "date"
+)
+
+// Which indexed versions `list` shows, and in which order.
+type ListFilter struct {
+	StableOnly bool
+	DevOnly    bool
+	Downloaded bool
+	// Oldest version to show, if set.
+	Since *Version
+	// Maximum number of versions to show, 0 for all of them.
+	Limit   int
+	Sort    string
+	Reverse bool
+}
+
+// Reads the list filter from the flags of `list`, exiting with a usage error
+// on invalid values.
+func listFilterFromArgs(args *Args) ListFilter {
+	filter := ListFilter{
+		StableOnly: args.Has("stable-only"),
+		DevOnly:    args.Has("dev-only"),
+		Downloaded: args.Has("downloaded"),
+		Sort:       SortVersion,
+		Reverse:    args.Has("reverse"),
+	}
+
+	if filter.StableOnly && filter.DevOnly {
+		usageError("--stable-only and --dev-only can't be used together.", "list")
+	}
+	if since, ok := args.Value("since"); ok {
+		version, err := ParseVersion(since)
+		if err != nil {
+			usageError(fmt.Sprintf("Invalid version %s for --since.", since), "list")
+		}
+		filter.Since = version
+	}
+	if limit, ok := args.Value("limit"); ok {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			usageError(fmt.Sprintf("Invalid limit %s.", limit), "list")
+		}
+		filter.Limit = n
+	}
+	if order, ok := args.Value("sort"); ok {
+		if order != SortVersion && order != SortDate {
+			usageError(fmt.Sprintf("Invalid sort order %s, expected version or date.", order), "list")
+		}
+		filter.Sort = order
+	}
+
+	return filter
+}
+
+func (filter *ListFilter) matches(item *Item) bool {
+	switch {
+	case !item.Indexed:
+		return false
+	case filter.StableOnly && item.channel() != ChannelStable:
+		return false
+	case filter.DevOnly && !item.Version.Dev:
+		return false
+	case filter.Downloaded && !item.Downloaded:
+		return false
+	case filter.Since != nil && item.Version.lessThan(*filter.Since):
+		return false
+	}
+
+	return true
+}
+
+// Returns the items the filter matches, newest first unless reversed, and
+// at most Limit of them.
+func (filter *ListFilter) apply(items []Item) []*Item {
+	result := []*Item{}
+	for i := 0; i < len(items); i++ {
+		if filter.matches(&items[i]) {
+			result = append(result, &items[i])
+		}
+	}
+
+	// Items are sorted by version already. Versions without a date go last.
+	if filter.Sort == SortDate {
+		sort.SliceStable(result, func(i, j int) bool {
+			return result[i].Date > result[j].Date
+		})
+	}
+	if filter.Reverse {
+		for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+			result[i], result[j] = result[j], result[i]
+		}
+	}
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+
+	return result
+}
@@ -0,0 +1,90 @@
+package main
+
+import "os"
+
+// Lock on ~/.zig-toolchain, held for the whole invocation. Commands that
+// change the local versions or the active one hold it exclusively, the others
+// share it, so that they never see a half-done change.
+type fileLock struct {
+	file      *os.File
+	exclusive bool
+}
+
+func lockPath() string {
+	return localDirPath("lock")
+}
+
+// Takes the lock, waiting for other instances holding it first.
+func acquireLock(exclusive bool) (*fileLock, error) {
+	file, err := os.OpenFile(lockPath(), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &fileLock{file: file}
+	if err = lock.set(exclusive); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return lock, nil
+}
+
+// Switches the lock to exclusive or shared, waiting for other instances if
+// needed.
+func (l *fileLock) set(exclusive bool) error {
+	ok, err := tryLockFile(l.file, exclusive)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		logger.warnf("Another instance of zig-toolchain is running, waiting for it to finish...\n")
+		if err = lockFile(l.file, exclusive); err != nil {
+			return err
+		}
+	}
+
+	l.exclusive = exclusive
+	return nil
+}
+
+// Reports whether the command changes the local state, and so needs the
+// lock exclusively.
+func commandIsMutating(command int) bool {
+	switch command {
+	case CommandList, CommandShow, CommandWhy, CommandResolve, CommandOutdated, CommandSmokeTest, CommandExec, CommandIndex, CommandInfo, CommandCompletion, CommandCurrent, CommandWhich, CommandSearch, CommandShell, CommandEnv:
+		return false
+	case CommandAsdf:
+		return len(os.Args) > 2 && os.Args[2] == "install"
+	}
+
+	return true
+}
+
+// Makes sure the lock is held exclusively, for commands that only change the
+// local state sometimes, e.g. exec installing a missing version.
+func (app *AppState) lockExclusively() {
+	if app.lock == nil || app.lock.exclusive {
+		return
+	}
+
+	if err := app.lock.set(true); err != nil {
+		app.fail(err)
+	}
+}
+
+func (app *AppState) holdsExclusiveLock() bool {
+	return app.lock != nil && app.lock.exclusive
+}
+
+// Releases the lock before handing over to a child process, which may run
+// for long and may well run zig-toolchain itself.
+func (app *AppState) releaseLock() {
+	if app.lock == nil {
+		return
+	}
+
+	app.lock.file.Close()
+	app.lock = nil
+}
@@ -0,0 +1,29 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func lockFlags(exclusive bool) int {
+	if exclusive {
+		return unix.LOCK_EX
+	}
+	return unix.LOCK_SH
+}
+
+func tryLockFile(file *os.File, exclusive bool) (bool, error) {
+	err := unix.Flock(int(file.Fd()), lockFlags(exclusive)|unix.LOCK_NB)
+	if err == unix.EWOULDBLOCK {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func lockFile(file *os.File, exclusive bool) error {
+	return unix.Flock(int(file.Fd()), lockFlags(exclusive))
+}
@@ -0,0 +1,38 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+func lockFileEx(file *os.File, flags uint32) error {
+	// Unlike flock, LockFileEx doesn't convert an existing lock, so it is
+	// released first.
+	overlapped := &windows.Overlapped{}
+	windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, overlapped)
+
+	return windows.LockFileEx(windows.Handle(file.Fd()), flags, 0, 1, 0, overlapped)
+}
+
+func lockFlags(exclusive bool) uint32 {
+	if exclusive {
+		return windows.LOCKFILE_EXCLUSIVE_LOCK
+	}
+	return 0
+}
+
+func tryLockFile(file *os.File, exclusive bool) (bool, error) {
+	err := lockFileEx(file, lockFlags(exclusive)|windows.LOCKFILE_FAIL_IMMEDIATELY)
+	if err == windows.ERROR_LOCK_VIOLATION {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func lockFile(file *os.File, exclusive bool) error {
+	return lockFileEx(file, lockFlags(exclusive))
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Levels of the messages printed while running a command. The output of the
+// command itself, e.g. the list of versions, is always printed.
+const (
+	// Only errors, with --quiet.
+	LogError = iota
+	// Progress, notes and warnings, the default.
+	LogInfo
+	// Resolved URLs, cache hits and HTTP status codes, with -v.
+	LogDebug
+	// Every extraction step, with -vv.
+	LogTrace
+)
+
+type Logger struct {
+	level int
+	// Print everything to stderr, for commands whose stdout is meant for
+	// eval or belongs to another program.
+	stderr bool
+}
+
+var logger = &Logger{level: LogInfo}
+
+// Sets the level from --quiet, --verbose, -v and -vv.
+func (l *Logger) setLevelFromArgs(args *Args) {
+	if args.Has("quiet") {
+		l.level = LogError
+	} else if verbose, ok := args.Value("verbose"); ok && verbose == "2" {
+		l.level = LogTrace
+	} else if args.Has("verbose") {
+		l.level = LogDebug
+	}
+}
+
+func (l *Logger) enabled(level int) bool {
+	return l.level >= level
+}
+
+// Returns where errors and progress are printed.
+func (l *Logger) out() *os.File {
+	if l.stderr {
+		return os.Stderr
+	}
+
+	return os.Stdout
+}
+
+func (l *Logger) errorf(format string, a ...interface{}) {
+	fmt.Fprintf(l.out(), format, a...)
+}
+
+func (l *Logger) infof(format string, a ...interface{}) {
+	if l.enabled(LogInfo) {
+		fmt.Fprintf(l.out(), format, a...)
+	}
+}
+
+// Warnings go to stderr, so that they don't get mixed into output that is
+// meant to be parsed.
+func (l *Logger) warnf(format string, a ...interface{}) {
+	if l.enabled(LogInfo) {
+		fmt.Fprintf(os.Stderr, format, a...)
+	}
+}
+
+func (l *Logger) debugf(format string, a ...interface{}) {
+	if l.enabled(LogDebug) {
+		fmt.Fprintf(os.Stderr, "debug: "+format, a...)
+	}
+}
+
+func (l *Logger) tracef(format string, a ...interface{}) {
+	if l.enabled(LogTrace) {
+		fmt.Fprintf(os.Stderr, "trace: "+format, a...)
+	}
+}
@@ -1,18 +1,24 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
 	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"text/template"
+	"time"
 	"github.com/fatih/color"
 )
 
@@ -21,36 +27,45 @@ const (
 )
 
 func zigBinPath() string {
+	if isTermux() {
+		return path.Join(termuxPrefix(), "bin", "zig")
+	}
+
+	if dir := localAppDataDir(); dir != "" {
+		return path.Join(dir, "zig-toolchain", "bin", zigExeName())
+	}
+
     return homeDirPath(".local", "bin", "zig")
 }
 
+// The home directory is checked to be known by run, before any path is built
+// from it.
 func homeDirPath(p ... string) string {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		panic(err)
-	}
-
+	home, _ := os.UserHomeDir()
 	return path.Join(append([]string{home}, p...)...)
 }
 
 func localDirPath(p ...string) string {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		panic(err)
+	if dir := localAppDataDir(); dir != "" {
+		return path.Join(append([]string{dir, "zig-toolchain"}, p...)...)
 	}
 
+	home, _ := os.UserHomeDir()
 	return path.Join(append([]string{home, ".zig-toolchain"}, p...)...)
 }
 
-func ensureDirectories() {
-	var err error
-	err = os.MkdirAll(localDirPath("tarballs"), os.ModePerm)
-	err = os.MkdirAll(localDirPath("current"), os.ModePerm)
-	if err != nil {
-		panic(err)
+func ensureDirectories() error {
+	for _, dir := range []string{"tarballs", "current", "versions", "src", "cache"} {
+		if err := os.MkdirAll(localDirPath(dir), os.ModePerm); err != nil {
+			return err
+		}
 	}
+
+	return nil
 }
 
+// Returns the name of the host's OS in the index. Unsupported hosts are
+// refused by run, so GOOS is only returned as is for help and --version.
 func getHostOs() string {
 	os := runtime.GOOS
 	switch os {
@@ -60,23 +75,58 @@ func getHostOs() string {
 		return "macos"
 	case "linux":
 		return os
+	case "android":
+		// The static linux builds run fine under Termux.
+		return "linux"
 	}
 
-	panic("Invalid os!")
+	return os
 }
 
+// Returns the name of the host's architecture in the index, or GOARCH as is
+// for unsupported hosts, like getHostOs.
 func getHostArch() string {
-	arch := runtime.GOARCH
-	switch arch {
-	case "386":
-		return "x86"
-	case "amd64":
-		return "x86-64"
-	case "arm64":
-		return "aarch64"
+	arch, ok := hostArches[runtime.GOARCH]
+	if !ok {
+		return runtime.GOARCH
 	}
 
-	panic("Invalid arch!")
+	return arch
+}
+
+// Names of the architectures that have official zig builds, keyed by GOARCH.
+var hostArches = map[string]string{
+	"386":     "x86",
+	"amd64":   "x86-64",
+	"arm64":   "aarch64",
+	"arm":     "arm",
+	"riscv64": "riscv64",
+	"ppc64le": "powerpc64le",
+	"loong64": "loongarch64",
+	"s390x":   "s390x",
+}
+
+// Reports whether there are official zig builds for the host.
+func isHostSupported() bool {
+	switch runtime.GOOS {
+	case "windows", "darwin", "linux", "android":
+	default:
+		return false
+	}
+
+	_, ok := hostArches[runtime.GOARCH]
+	return ok
+}
+
+// Returns the names of the host's architecture in the index and in tarball
+// names. 32-bit ARM builds were named armv7a before being renamed to arm.
+func hostArchNames() []string {
+	arch := strings.ReplaceAll(getHostArch(), "-", "_")
+	if arch == "arm" {
+		return []string{"arm", "armv7a"}
+	}
+
+	return []string{arch}
 }
 
 func localTarballPathFromUrl(url string) string {
@@ -85,12 +135,34 @@ func localTarballPathFromUrl(url string) string {
 	return localDirPath("tarballs", filename)
 }
 
-func extractedDirForVersion(v Version) string {
-	fname := fmt.Sprintf("zig-%s-%s-%d.%d.%d", getHostOs(), getHostArch(), v.Major, v.Minor, v.Patch)
-	if v.Dev {
-		fname += fmt.Sprintf("-dev.%d+%s", v.Build, v.Commit)
+// Name of the top-level directory inside a tarball, which matches the
+// tarball's file name without its extension.
+func tarballBaseName(tarballPath string) string {
+	name := path.Base(tarballPath)
+	return strings.TrimSuffix(name, archiveExt(name))
+}
+
+func archiveExt(tarballPath string) string {
+	for _, ext := range []string{".tar.xz", ".zip"} {
+		if strings.HasSuffix(tarballPath, ext) {
+			return ext
+		}
 	}
-	return localDirPath("current", fname)
+	return ""
+}
+
+// Extension of the release tarballs for the host.
+func tarballExt() string {
+	if getHostOs() == "windows" {
+		return ".zip"
+	}
+	return ".tar.xz"
+}
+
+// Every version is extracted once into its own directory in versions/, so
+// switching between extracted versions only needs relinking.
+func extractedDirForItem(item *Item) string {
+	return localDirPath("versions", tarballBaseName(item.LocalPath))
 }
 
 type Item struct {
@@ -101,197 +173,241 @@ type Item struct {
 	Master     bool
 	LocalPath  string
 	RemoteUrl  string
+	Size       int64
+	Shasum     string
+	Platforms  []string
+	Date       string
+	Docs       string
+	StdDocs    string
+	Emulated   bool
+	Custom     bool
+	CustomName string
+	Src        *ZigIndexFileEntry
+	Bootstrap  *ZigIndexFileEntry
+	Files      map[string]*ZigIndexFileEntry
+
+	// URL of the tarball in the index, before any rewrite rule applies.
+	OriginalUrl string
+	// Label of the index source the item comes from, empty for the main
+	// index.
+	SourceLabel string
+	// Names under which Mach nominated the version, e.g. 2024.10.0-mach.
+	Nominations []string
+	// Looked up on the builds server, which has no shasums to check the
+	// tarball against.
+	FromBuilds bool
 }
 
-type Version struct {
-	Major  int
-	Minor  int
-	Patch  int
-	Dev    bool
-	Build  int
-	Commit string
+// Name to show for the item: the version, or the name of custom toolchains.
+func (item *Item) Name() string {
+	if item.Custom {
+		return item.CustomName
+	}
+
+	// Dev builds are named by their full version, which tells the builds of
+	// different days apart and can be passed back to activate.
+	return item.Version.FullString()
 }
 
-func (v Version) String() string {
-	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
-	if v.Dev {
-		s += fmt.Sprintf("-dev-%d", v.Build)
-	}
-	return s
+type AppState struct {
+	Items     []Item
+	Config    *Config
+	Notify    bool
+	AssumeYes bool
+	DryRun    bool
+	Json      bool
+	Format    *template.Template
+	Args      *Args
+	LinkMode  string
+	NoNetwork bool
+	Offline   bool
+
+	ProgressFormat string
+	OverridePolicy bool
+	Force          bool
+
+	ParallelDownloads bool
+	StreamExtract     bool
+	DeleteTarball     bool
+
+	// Index snapshot used instead of the index sources.
+	IndexFile string
+	// The indexes the items were built from.
+	Indexes []SourceIndex
+
+	client          *http.Client
+	ctx             context.Context
+	cancel          context.CancelFunc
+	aliases         map[string]string
+	machFetched     bool
+	machVersion     *Version
+	credentialCache map[string]*Credentials
+	credentialMutex sync.Mutex
+	stateMutex      sync.Mutex
+	lock            *fileLock
+	registry        *ToolchainRegistry
 }
 
-func (v Version) equal(other Version) bool {
-	if v.Dev || other.Dev {
-		if !(v.Dev && other.Dev) {
-			return false
+func (app *AppState) GetCurrentActiveItem() (*Item, bool) {
+	for i := 0; i < len(app.Items); i++ {
+		if app.Items[i].Current {
+			return &app.Items[i], true
 		}
-
-		return v.Major == other.Major && v.Minor == other.Minor && v.Patch == other.Patch && v.Build == other.Build
 	}
 
-	return v.Major == other.Major && v.Minor == other.Minor && v.Patch == other.Patch
+	return nil, false
 }
 
-func (v Version) lessThan(other Version) bool {
-	if v.Major == other.Major {
-		if v.Minor == other.Minor {
-			if v.Patch == other.Patch {
-				if v.Dev && other.Dev {
-					return v.Build < other.Build
-				} else if v.Dev && !other.Dev {
-					return false
-				} else if !v.Dev && other.Dev {
-					return true
-				} else {
-					return false
-				}
-			} else {
-				return v.Patch < other.Patch
-			}
-		} else {
-			return v.Minor < other.Minor
+func (app *AppState) GetItemByVersion(v Version) (*Item, bool) {
+	for i := 0; i < len(app.Items); i++ {
+		var item = &app.Items[i]
+		if !item.Custom && item.Version.equal(v) {
+			return item, true
 		}
-	} else {
-		return v.Major < other.Major
 	}
+
+	return nil, false
 }
 
-func (v Version) moreThan(other Version) bool {
-	return !v.lessThan(other)
+func NewAppState() *AppState {
+	return &AppState{Items: []Item{}, Config: NewConfig()}
 }
 
-// Given a version string in the form 0.10.1, or 0.11.2-dev-1234+a3f634,
-// return the corresponding Version object.
-func ParseVersion(v string) (*Version, error) {
-	result := &Version{}
+type ZigIndex struct {
+	Entries map[string]ZigIndexEntry
+}
 
-	sp := strings.Split(v, "-")
-	sp2 := strings.Split(sp[0], ".")
+type ZigIndexEntry struct {
+	Version   string             `json:"version,omitempty"`
+	Date      string             `json:"date,omitempty"`
+	Docs      string             `json:"docs,omitempty"`
+	StdDocs   string             `json:"stdDocs,omitempty"`
+	Notes     string             `json:"notes,omitempty"`
+	Src       *ZigIndexFileEntry `json:"src,omitempty"`
+	Bootstrap *ZigIndexFileEntry `json:"bootstrap,omitempty"`
+
+	// The published tarballs, keyed by target (e.g. `x86_64-linux`).
+	Files map[string]*ZigIndexFileEntry `json:"-"`
+}
 
-	if len(sp2) != 3 {
-		return nil, errors.New(fmt.Sprintf("Failed to parse version: %s", v))
+// Unmarshals an index entry, where every key besides the metadata is a
+// target, so that targets added upstream are picked up as they come.
+func (z *ZigIndexEntry) UnmarshalJSON(data []byte) error {
+	type metadata ZigIndexEntry
+	if err := json.Unmarshal(data, (*metadata)(z)); err != nil {
+		return err
 	}
 
-	major, err := strconv.ParseInt(sp2[0], 10, 32)
-	minor, err := strconv.ParseInt(sp2[1], 10, 32)
-	patch, err := strconv.ParseInt(sp2[2], 10, 32)
-
-	if err != nil {
-		return nil, err
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
 	}
 
-	result.Major = int(major)
-	result.Minor = int(minor)
-	result.Patch = int(patch)
+	z.Files = map[string]*ZigIndexFileEntry{}
+	for key, value := range fields {
+		switch key {
+		case "version", "date", "docs", "stdDocs", "notes", "src", "bootstrap":
+			continue
+		}
 
-	if len(sp) > 1 {
-		result.Dev = true
-		sp2 = strings.Split(strings.Split(sp[1], ".")[1], "+")
-		build, err := strconv.ParseInt(sp2[0], 10, 32)
-		if err != nil {
-			return nil, err
+		// Metadata that is not known yet, rather than a target.
+		entry := &ZigIndexFileEntry{}
+		if err := json.Unmarshal(value, entry); err != nil || entry.Tarball == "" {
+			continue
 		}
-		result.Build = int(build)
-		result.Commit = sp2[1]
+		z.Files[key] = entry
 	}
 
-	return result, nil
+	return nil
 }
 
-type AppState struct {
-	Items []Item
+// Marshals an index entry the way it is unmarshaled, with the targets next
+// to the metadata.
+func (z ZigIndexEntry) MarshalJSON() ([]byte, error) {
+	type metadata ZigIndexEntry
+	data, err := json.Marshal(metadata(z))
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]interface{}{}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for target, entry := range z.Files {
+		fields[target] = entry
+	}
+
+	return json.Marshal(fields)
 }
 
-func (app *AppState) GetCurrentActiveItem() (*Item, bool) {
-	for _, item := range app.Items {
-		if item.Current {
-			return &item, true
-		}
+// Returns the targets of the host as named in the index, e.g.
+// `x86_64-linux`.
+func hostTargets() []string {
+	result := []string{}
+	for _, arch := range hostArchNames() {
+		result = append(result, arch+"-"+getHostOs())
 	}
 
-	return nil, false
+	return result
 }
 
-func (app *AppState) GetItemByVersion(v Version) (*Item, bool) {
-	for i := 0; i < len(app.Items); i++ {
-		var item = &app.Items[i]
-		if item.Version.equal(v) {
-			return item, true
+func (z *ZigIndexEntry) GetFileEntryForHost() *ZigIndexFileEntry {
+	for _, target := range hostTargets() {
+		if entry, ok := z.Files[target]; ok {
+			return entry
 		}
 	}
 
-	return nil, false
-}
+	// Older releases have no ARM64 build for Windows, the x86_64 one runs
+	// under emulation.
+	if getHostOs() == "windows" && getHostArch() == "aarch64" {
+		return z.Files["x86_64-windows"]
+	}
 
-func NewAppState() *AppState {
-	return &AppState{Items: []Item{}}
+	return nil
 }
 
-type ZigIndex struct {
-	Entries map[string]ZigIndexEntry
+type ZigIndexTarget struct {
+	Name  string
+	Entry *ZigIndexFileEntry
 }
 
-type ZigIndexEntry struct {
-	Version           string             `json:"version"`
-	Date              string             `json:"date"`
-	Docs              string             `json:"docs"`
-	StdDocs           string             `json:"stdDocs"`
-	Src               *ZigIndexFileEntry `json:"src"`
-	Bootstrap         *ZigIndexFileEntry `json:"bootstrap"`
-	X86_64_macos      *ZigIndexFileEntry `json:"x86_64-macos"`
-	Aarch64_macos     *ZigIndexFileEntry `json:"aarch64-macos"`
-	X86_64_linux      *ZigIndexFileEntry `json:"x86_64-linux"`
-	Aarch64_linux     *ZigIndexFileEntry `json:"aarch64-linux"`
-	Riscv64_linux     *ZigIndexFileEntry `json:"riscv64-linux"`
-	Powerpc64le_linux *ZigIndexFileEntry `json:"powerpc64le-linux"`
-	Powerpc_linux     *ZigIndexFileEntry `json:"powerpc-linux"`
-	X86_linux         *ZigIndexFileEntry `json:"x86-linux"`
-	X86_64_windows    *ZigIndexFileEntry `json:"x86_64-windows"`
-	Aarch64_windows   *ZigIndexFileEntry `json:"aarch64-windows"`
-	X86_windows       *ZigIndexFileEntry `json:"x86-windows"`
-}
+// Returns all the targets of this entry, in a stable order.
+func (z *ZigIndexEntry) Targets() []ZigIndexTarget {
+	result := []ZigIndexTarget{}
+	for name, entry := range z.Files {
+		result = append(result, ZigIndexTarget{name, entry})
+	}
 
-func (z *ZigIndexEntry) GetFileEntryForHost() *ZigIndexFileEntry {
-	os := getHostOs()
-	arch := getHostArch()
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
 
-	switch os {
-	case "macos":
-		switch arch {
-		case "aarch64":
-			return z.Aarch64_macos
-		case "x86-64":
-			return z.X86_64_macos
-		}
+	return result
+}
 
-	case "linux":
-		switch arch {
-		case "aarch64":
-			return z.Aarch64_linux
-		case "x86-64":
-			return z.X86_64_linux
-		case "x86":
-			return z.X86_linux
-		}
+// Returns the published tarballs (e.g. `x86_64-linux`) for this entry, keyed
+// by target.
+func (z *ZigIndexEntry) FileEntries() map[string]*ZigIndexFileEntry {
+	return z.Files
+}
 
-	case "windows":
-		switch arch {
-		case "aarch64":
-			return z.Aarch64_windows
-		case "x86-64":
-			return z.X86_64_windows
-		case "x86":
-			return z.X86_windows
-		}
+// Returns the targets (e.g. `x86_64-linux`) that have a published tarball for
+// this entry.
+func (z *ZigIndexEntry) Platforms() []string {
+	result := []string{}
+	for _, t := range z.Targets() {
+		result = append(result, t.Name)
 	}
 
-	panic("invalid os/arch!")
+	return result
 }
 
 type ZigIndexFileEntry struct {
-	Tarball string
-	Shasum  string
-	Size    string
+	Tarball string `json:"tarball"`
+	Shasum  string `json:"shasum"`
+	Size    string `json:"size"`
 }
 
 func NewZigIndex() *ZigIndex {
@@ -300,24 +416,61 @@ func NewZigIndex() *ZigIndex {
 	}
 }
 
-func FetchIndex() (*ZigIndex, error) {
-	result := NewZigIndex()
+// Fetches an index in the format of the ziglang.org one from url.
+func (app *AppState) fetchIndexFrom(url string) (*ZigIndex, error) {
+	body, _, err := app.fetchIndexData(url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseIndex(body)
+}
+
+// Downloads the raw JSON of the index at url. With the validators of a
+// cached copy, nil is returned if the index didn't change since. The
+// validators of the returned index are returned along with it.
+func (app *AppState) fetchIndexData(url string, validators *CacheValidators) ([]byte, *CacheValidators, error) {
+	ctx, watchBody, cancel := app.requestContext()
+	defer cancel()
 
 	// Download the JSON file
-	resp, err := http.Get(IndexUrl)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	app.authorizeRequest(req)
+	if validators != nil {
+		validators.apply(req)
+	}
+
+	resp, err := app.httpClient().Do(req)
+	if err != nil {
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified && validators != nil {
+		logger.debugf("%s has not changed since it was cached\n", url)
+		return nil, validators, nil
+	}
+
+	if err = checkResponseStatus(resp); err != nil {
+		return nil, nil, err
+	}
+
 	// Read the body of the response
-	body, err := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(watchBody(resp.Body))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	// var f map[string]ZigIndexEntry
-	err = json.Unmarshal(body, &result.Entries)
+	return body, validatorsFromResponse(resp), nil
+}
+
+func parseIndex(data []byte) (*ZigIndex, error) {
+	result := NewZigIndex()
+
+	err := json.Unmarshal(data, &result.Entries)
 	if err != nil {
 		return nil, err
 	}
@@ -325,79 +478,203 @@ func FetchIndex() (*ZigIndex, error) {
 	return result, nil
 }
 
-func (app *AppState) commandListRemote() {
+// Returns the item for the host of the index entry with the given key, if
+// there is a build for the host. Entries with an explicit version are master
+// builds.
+func (app *AppState) indexItem(key string, entry ZigIndexEntry) (Item, bool) {
+	item := Item{}
+	fileEntry := entry.GetFileEntryForHost()
+	if fileEntry == nil {
+		return item, false
+	}
+
+	versionString := entry.Version
+	if versionString == "" {
+		versionString = key
+	} else {
+		item.Master = true
+	}
+
+	version, err := ParseVersion(versionString)
+	if err != nil {
+		logger.debugf("Skipping %s, which has an invalid version: %s\n", key, err)
+		return item, false
+	}
+
+	item.Version = *version
+	item.Indexed = true
+	item.RemoteUrl = app.Config.rewriteUrl(fileEntry.Tarball)
+	item.OriginalUrl = fileEntry.Tarball
+	item.LocalPath = localTarballPathFromUrl(fileEntry.Tarball)
+	item.Size, _ = strconv.ParseInt(fileEntry.Size, 10, 64)
+	item.Shasum = fileEntry.Shasum
+	item.Platforms = entry.Platforms()
+	item.Date = entry.Date
+	item.Docs = entry.Docs
+	item.StdDocs = entry.StdDocs
+	item.Src = entry.Src
+	item.Bootstrap = entry.Bootstrap
+	item.Files = entry.FileEntries()
+	item.Emulated = getHostOs() == "windows" && getHostArch() == "aarch64" && fileEntry == entry.Files["x86_64-windows"]
+
+	return item, true
+}
+
+func (app *AppState) commandListRemote(showPlatforms bool, filter ListFilter) {
+	items := filter.apply(app.Items)
+	if app.structuredOutput() {
+		app.printItems(items)
+		return
+	}
+
     green := color.New(color.FgGreen).SprintFunc()
     blue := color.New(color.FgBlue).SprintFunc()
-    red := color.New(color.FgRed).SprintFunc()
 	fmt.Printf("List of indexed zig versions (%s %s):  \n\n", green("[active]"), blue("[downloaded]"))
-	for _, item := range app.Items {
-		if item.Indexed {
-            if item.Current {
-                fmt.Printf("%s %s", green("==>"), green(item.Version.String()))
-            } else if item.Downloaded {
-                fmt.Printf("%s %s", blue("==>"), blue(item.Version.String()))
-            } else {
-                fmt.Printf("==> %s", item.Version.String())
-            }
-
-            if item.Master {
-                fmt.Printf(" %s ", red("[master]"))
-            }
+	app.printItemTable(items, showPlatforms)
+}
 
-			fmt.Printf("\n")
+func (app *AppState) commandListLocal() {
+	if app.structuredOutput() {
+		items := []*Item{}
+		for i := 0; i < len(app.Items); i++ {
+			if app.Items[i].Downloaded || app.Items[i].Custom {
+				items = append(items, &app.Items[i])
+			}
 		}
+		app.printItems(items)
+		return
 	}
-}
 
-func (app *AppState) commandListLocal() {
     green := color.New(color.FgGreen).SprintFunc()
-    red := color.New(color.FgRed).SprintFunc()
     fmt.Printf("List of downloaded zig versions (%s): \n\n", green("[active]"))
-	for _, item := range app.Items {
-		if item.Downloaded {
-			// fmt.Printf("  -%s", item.Version.String())
-			// if item.Current {
-			// 	fmt.Printf(" [current]")
-			// }
-
-            if item.Current {
-                fmt.Printf("%s %s", green("==>"), green(item.Version.String()))
-            } else {
-                fmt.Printf("==> %s", item.Version.String())
-            }
-
-            if item.Master {
-                fmt.Printf(" %s ", red("[master]"))
-            }
-
-			fmt.Printf("\n")
+	items := []*Item{}
+	for i := 0; i < len(app.Items); i++ {
+		if app.Items[i].Downloaded || app.Items[i].Custom {
+			items = append(items, &app.Items[i])
 		}
 	}
+	app.printItemTable(items, false)
 }
 
 func (app *AppState) downloadTarball(item Item) error {
-	fmt.Printf("Downlading tarball %s...", item.RemoteUrl)
-	res, err := http.Get(item.RemoteUrl)
+	if app.Config.Concurrency > 1 {
+		if size, ok := app.supportsRanges(item.RemoteUrl); ok {
+			return app.downloadTarballChunked(item, size)
+		}
+	}
+
+	logger.infof("Downloading tarball %s...\n", item.RemoteUrl)
+	ctx, watchBody, cancel := app.requestContext()
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", item.RemoteUrl, nil)
+	if err != nil {
+		return err
+	}
+	app.authorizeRequest(req)
+
+	res, err := app.httpClient().Do(req)
 	if err != nil {
 		return err
 	}
 	defer res.Body.Close()
 
-	data, err := io.ReadAll(res.Body)
+	if err = checkResponseStatus(res); err != nil {
+		return err
+	}
+
+	version := item.Version.FullString()
+	app.emitProgress(ProgressEvent{Event: "download_started", Version: version, Url: item.RemoteUrl, Total: item.Size})
+
+	total := res.ContentLength
+	if total <= 0 {
+		total = item.Size
+	}
+
+	bar := app.newProgressBar(total)
+	body := &progressReader{reader: watchBody(res.Body), onProgress: func(read int64) {
+		bar.update(read)
+		app.emitProgress(ProgressEvent{Event: "download_progress", Version: version, Bytes: read, Total: total})
+	}}
+
+	// Stream into a temporary file that is only renamed into place once the
+	// download is complete and verified, so an interrupted download never
+	// leaves a corrupt tarball behind.
+	file, err := os.CreateTemp(path.Dir(item.LocalPath), ".download-*")
 	if err != nil {
 		return err
 	}
+	defer os.Remove(file.Name())
+
+	hash := sha256.New()
+	writers := []io.Writer{file, hash}
+
+	// Extract while downloading, keeping the result only if the download
+	// turns out complete and verified.
+	var stream *streamExtraction
+	if app.shouldStreamExtract(&item) {
+		stream, err = startStreamExtraction()
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(stream.tmp)
+		writers = append(writers, stream.writer)
+		app.emitProgress(ProgressEvent{Event: "extract_started", Version: version, Path: item.LocalPath})
+	}
 
-	file, err := os.Create(item.LocalPath)
+	written, err := io.Copy(io.MultiWriter(writers...), body)
+	bar.finish(written)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if stream != nil {
+		if extractErr := stream.wait(err); err == nil {
+			err = extractErr
+		}
+	}
 	if err != nil {
 		return err
 	}
-	_, err = file.Write(data)
+
+	if res.ContentLength > 0 && written != res.ContentLength {
+		return fmt.Errorf("truncated download of %s: got %d of %d bytes", item.RemoteUrl, written, res.ContentLength)
+	}
+
+	err = app.finishDownload(item, file.Name(), hex.EncodeToString(hash.Sum(nil)), written)
+	if err != nil || stream == nil {
+		return err
+	}
+
+	return app.finishStreamExtraction(stream, &item)
+}
+
+// Verifies a completely downloaded temporary file against the size and
+// shasum of item and moves it into place.
+func (app *AppState) finishDownload(item Item, tmpPath string, sum string, written int64) error {
+	version := item.Version.FullString()
+
+	if item.Size > 0 && written != item.Size {
+		return fmt.Errorf("size mismatch for %s: expected %d bytes from the index, got %d", item.RemoteUrl, item.Size, written)
+	}
+
+	if item.Shasum != "" {
+		err := checkSha256(item.RemoteUrl, sum, item.Shasum)
+		if err != nil && !app.Force {
+			return err
+		} else if err != nil {
+			logger.warnf("\nWarning: %s, keeping it because of --force.\n", err)
+		} else {
+			app.emitProgress(ProgressEvent{Event: "checksum_verified", Version: version, Url: item.RemoteUrl})
+		}
+	}
+
+	err := os.Rename(tmpPath, item.LocalPath)
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("Done!\n")
+	app.emitProgress(ProgressEvent{Event: "download_finished", Version: version, Path: item.LocalPath, Bytes: written})
+	logger.infof("Done!\n")
 
 	return nil
 }
@@ -411,96 +688,242 @@ func (app *AppState) commandDownloadMaster() {
 		}
 	}
 
-	panic("Master version not found!")
+	app.fail(&VersionNotFoundError{Name: ChannelMaster})
 }
 
 func (app *AppState) commandDownloadVersion(v Version) {
 	if item, ok := app.GetItemByVersion(v); ok {
 		app.commandDownloadItem(item)
+	} else if item, err := app.historicalDevItem(v); err == nil {
+		app.commandDownloadItem(item)
 	} else {
-		fmt.Printf("Invalid version!")
-		os.Exit(1)
+		app.fail(err)
 	}
 }
 
 func (app *AppState) commandDownloadItem(item *Item) {
+	app.enforcePolicy(item)
+
 	if item.Downloaded {
-		fmt.Print("Tarball already downloaded!\n")
+		logger.infof("Tarball already downloaded!\n")
 		return
 	}
 
-	if !item.Indexed {
-		fmt.Printf("Item not indexed!")
+	if !app.DryRun && item.Indexed && !app.confirm(fmt.Sprintf("Download zig %s (%s)?", item.Version.String(), item.sizeDescription())) {
+		logger.errorf("Aborted.\n")
 		os.Exit(1)
 	}
 
-	err := app.downloadTarball(*item)
+	err := app.downloadItem(item)
+	if err != nil {
+		app.fail(err)
+	}
+}
+
+// Downloads the tarball of item if it isn't downloaded yet, without
+// prompting.
+func (app *AppState) downloadItem(item *Item) error {
+	if err := app.policyError(item); err != nil {
+		return err
+	}
+
+	if item.Downloaded {
+		return nil
+	}
+
+	if err := app.networkError(fmt.Sprintf("download zig %s", item.Version.String())); err != nil {
+		return err
+	}
+
+	if item.Custom || item.RemoteUrl == "" {
+		return fmt.Errorf("zig %s is not in the index", item.Name())
+	}
+
+	if item.Emulated {
+		logger.warnf("Note: there is no aarch64-windows build of zig %s, using the x86_64 build under emulation.\n", item.Version.String())
+	}
+
+	if app.DryRun {
+		fmt.Printf("Would download %s to %s (%s)\n", item.RemoteUrl, item.LocalPath, item.sizeDescription())
+		return nil
+	}
+
+	if err := checkDiskSpace(localDirPath("tarballs"), item.Size); err != nil {
+		return err
+	}
+
+	err := app.downloadFromSources(item)
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	// The builds server may redirect or serve another build under the name
+	// asked for, so check that the tarball really holds it.
+	if item.FromBuilds {
+		if err = checkBuildVersion(item.LocalPath, item.Version); err != nil {
+			os.Remove(item.LocalPath)
+			return err
+		}
+	}
+
+	// Without a shasum from the index, at least make sure the tarball holds
+	// the version it is named after.
+	if item.Shasum == "" {
+		if err = validateTarball(item.LocalPath); err != nil {
+			os.Remove(item.LocalPath)
+			return err
+		}
 	}
 
 	item.Downloaded = true
+	app.recordDownloaded(item)
+	app.notify(fmt.Sprintf("Downloaded zig %s", item.Version.String()))
+
+	return nil
 }
 
 func (app *AppState) commandActivateMaster() {
-	for i := 0; i < len(app.Items); i++ {
-		item := &app.Items[i]
-		if item.Master {
-			app.commandActivateItem(item)
-			return
-		}
+	item, ok := app.masterItem()
+	if !ok {
+		app.fail(&VersionNotFoundError{Name: ChannelMaster})
 	}
 
-	fmt.Printf("Version not found!\n")
-	os.Exit(1)
+	app.commandActivateItem(item)
 }
 
 func (app *AppState) commandActivateVersion(v Version) {
 	item, ok := app.GetItemByVersion(v)
 	if !ok {
-		fmt.Printf("Version not found!\n")
-		os.Exit(1)
+		var err error
+		if item, err = app.historicalDevItem(v); err != nil {
+			app.fail(err)
+		}
 	}
     app.commandActivateItem(item)
 }
 
 func (app *AppState) commandActivateItem(item *Item) {
 	if item.Current {
-		fmt.Printf("Version is already active!")
+		logger.infof("Version is already active!")
 		os.Exit(0)
 	}
 
-	if !item.Downloaded {
-		app.commandDownloadItem(item)
+	app.enforcePolicy(item)
+
+	if app.DryRun {
+		app.printActivationPlan(item)
+		return
 	}
 
-    os.RemoveAll(localDirPath("current"))
-    ensureDirectories()
+	if item.Custom {
+		logger.infof("Creating %s...", app.LinkMode)
+		err := app.switchToolchain(item.LocalPath, currentCustomToolchainPath(), item.CustomName)
+		if err != nil {
+			app.fail(err)
+		}
+		logger.infof("Done!\n")
+		app.removeLegacyCurrentDirs()
+		recordActivation(item)
+		app.emitProgress(ProgressEvent{Event: "activated", Version: item.Name(), Path: zigBinPath()})
+		return
+	}
 
+	dir := extractedDirForItem(item)
+	if !isExtracted(dir) {
+		needed := item.extractedSizeEstimate()
+		if !item.Downloaded {
+			needed += item.Size
+		}
+		if err := checkDiskSpace(localDirPath("versions"), needed); err != nil {
+			app.fail(err)
+		}
 
-    fmt.Printf("Extracting...")
-	cmd := exec.Command("tar", "-xf", item.LocalPath)
-	cmd.Dir = localDirPath("current")
-	out, err := cmd.CombinedOutput()
+		if !item.Downloaded {
+			app.StreamExtract = app.Config.StreamExtract
+			app.commandDownloadItem(item)
+		} else if err := app.verifyTarball(item); err != nil {
+			if !app.Force {
+				logger.errorf("Refusing to activate zig %s, pass --force to activate it anyway.\n", item.Version.String())
+				app.fail(err)
+			}
+			logger.warnf("Warning: %s, activating anyway because of --force.\n", err)
+		}
+	}
+
+	if !isExtracted(dir) {
+		logger.infof("Extracting %s...\n", path.Base(item.LocalPath))
+		app.emitProgress(ProgressEvent{Event: "extract_started", Version: item.Version.FullString(), Path: item.LocalPath})
+		var bar *progressBar
+		var extracted int64
+		err := app.extractItem(item, func(read int64, total int64) {
+			if bar == nil {
+				bar = app.newProgressBar(total)
+			}
+			extracted = read
+			bar.update(read)
+			app.emitProgress(ProgressEvent{Event: "extract_progress", Version: item.Version.FullString(), Bytes: read, Total: total})
+		})
+		if bar != nil {
+			bar.finish(extracted)
+		}
+		if err != nil {
+			app.fail(err)
+		}
+		app.emitProgress(ProgressEvent{Event: "extract_finished", Version: item.Version.FullString(), Path: dir})
+		logger.infof("Done!\n")
+	}
+
+	// link
+	logger.infof("Creating %s...", app.LinkMode)
+	err := app.switchToolchain(dir, currentVersionPath(), path.Base(dir))
 	if err != nil {
-		panic(string(out))
-	}
-    fmt.Printf("Done!\n")
-
-    // link
-    fmt.Printf("Creating symlink...")
-    _, err =  os.Lstat(zigBinPath())
-    if err == nil {
-        err = os.Remove(zigBinPath())
-        if err != nil {
-            panic(err)
-        }
-    }
-    err = os.Symlink(path.Join(extractedDirForVersion(item.Version), "zig"), zigBinPath())
-    if err != nil {
-        panic(err)
-    }
-    fmt.Printf("Done!\n")
+		app.fail(err)
+	}
+	logger.infof("Done!\n")
+	app.removeLegacyCurrentDirs()
+	recordActivation(item)
+	app.emitProgress(ProgressEvent{Event: "activated", Version: item.Version.FullString(), Path: zigBinPath()})
+
+	if app.DeleteTarball {
+		if _, err := app.removeExtractedTarball(item); err != nil {
+			logger.warnf("Failed to delete %s: %s\n", item.LocalPath, err)
+		}
+	}
+}
+
+// Removes everything activation exposed outside of ~/.zig-toolchain and
+// clears the active version, so a system-installed zig is used again.
+func (app *AppState) commandDeactivate() {
+	item, ok := app.GetCurrentActiveItem()
+	if !ok {
+		if _, err := os.Lstat(zigBinPath()); err != nil {
+			logger.infof("No active version!\n")
+			os.Exit(0)
+		}
+	}
+
+	err := unlinkToolchain()
+	if err != nil {
+		app.fail(err)
+	}
+	os.Remove(activeDirPath())
+	os.Remove(currentVersionPath())
+	os.Remove(currentCustomToolchainPath())
+	if err = ensureDirectories(); err != nil {
+		app.fail(err)
+	}
+	app.recordActive("")
+
+	if ok {
+		logger.infof("Deactivated zig %s.\n", item.Name())
+	} else {
+		logger.infof("Deactivated.\n")
+	}
+	app.removeLegacyCurrentDirs()
+
+	if zig, err := exec.LookPath("zig"); err == nil {
+		logger.infof("zig now resolves to %s\n", zig)
+	}
 }
 
 const (
@@ -509,153 +932,197 @@ const (
 	CommandShow
 	CommandActivate
     CommandDeactivate
+	CommandPin
+	CommandWhy
+	CommandGc
+	CommandSmokeTest
+	CommandBisect
+	CommandAsdf
+	CommandExec
+	CommandLink
+	CommandInstall
+	CommandDedupe
+	CommandRemove
+	CommandPrune
+	CommandUpgrade
+	CommandOutdated
+	CommandRollback
+	CommandAlias
+	CommandAdopt
+	CommandResolve
+	CommandUpdate
+	CommandIndex
+	CommandInfo
+	CommandCompletion
+	CommandCurrent
+	CommandWhich
+	CommandSearch
+	CommandShell
+	CommandDefault
+	CommandEnv
 	CommandNone
 )
 
-func printUsageAndExit() {
-	fmt.Printf("USAGE: zig-toolchain [COMMAND]\n\n")
-	fmt.Printf("COMMANDS:")
-	fmt.Printf("\n    download\t\t Download a zig version.")
-	fmt.Printf("\n    list\t\t List remote versions.")
-	fmt.Printf("\n    show\t\t List local versions.")
-	fmt.Printf("\n    activate\t\t Activeate a given zig version.")
-	fmt.Printf("\n    deactivate\t\t Deactivate the current active version. Removes the symlink to the zig binary.")
-	fmt.Printf("\n\n")
-	os.Exit(0)
-}
-
-func (app *AppState) run() {
+// Returns the index of source: the cached one while it's fresh, otherwise a
+// newly fetched one. When the network is disabled or unreachable, the cached
+// index is used however old it is, and nil is returned if there is none.
+// Only the main index takes zig-toolchain offline when it's unreachable, and
+// is retried; the other sources are best effort.
+func (app *AppState) loadIndex(source IndexSource, command int) (*ZigIndex, error) {
+	index, ok := app.cachedIndex(source.Url)
+	if ok && command != CommandUpdate {
+		return index, nil
+	}
 
-	if len(os.Args) < 2 {
-        printUsageAndExit()
+	main := source.Url == app.Config.indexUrl()
+	index = nil
+	if !app.NoNetwork {
+		var err error
+		if index, err = app.refreshIndex(source.Url, main); err != nil {
+			if !isUnreachable(err) || command == CommandUpdate {
+				return nil, err
+			}
+			if main {
+				app.goOffline(err)
+			} else if _, _, ok := readCachedIndex(source.Url); !ok {
+				return nil, err
+			}
+		}
+	}
+	if cached, fetched, ok := readCachedIndex(source.Url); index == nil && ok && command != CommandUpdate {
+		name := "the release index"
+		if !main {
+			name = "the index of " + source.description()
+		}
+		logger.warnf("Using %s cached on %s.\n", name, fetched.Format("2006-01-02 15:04"))
+		index = cached
 	}
 
-	command := CommandNone
+	return index, nil
+}
 
-	switch os.Args[1] {
-	case "download":
-		command = CommandDownload
-	case "list":
-		command = CommandList
-	case "show":
-		command = CommandShow
-	case "activate":
-		command = CommandActivate
-    case "deactivate":
-        command = CommandDeactivate
-	default:
-		printUsageAndExit()
+// Reports whether the command needs the release index. Commands that act on
+// a version only need it when the version isn't available locally.
+func (app *AppState) commandNeedsIndex(command int) bool {
+	switch command {
+	case CommandList, CommandDownload, CommandAsdf, CommandUpgrade, CommandOutdated, CommandUpdate, CommandBisect, CommandIndex, CommandInfo, CommandSearch:
+		return true
+	case CommandGc, CommandPrune:
+		return app.projectPinsNeedIndex()
+	case CommandWhy:
+		if app.projectPinsNeedIndex() {
+			return true
+		}
+		return len(app.Args.Positional) > 0 && !app.availableLocally(app.Args.Positional[0])
+	case CommandActivate, CommandDefault, CommandPin, CommandResolve, CommandSmokeTest, CommandShell, CommandEnv:
+		if len(app.Args.Positional) == 0 {
+			return false
+		}
+		return !app.availableLocally(app.Args.Positional[0])
+	case CommandExec:
+		if len(app.Args.Positional) > 0 {
+			return !app.availableLocally(app.Args.Positional[0])
+		}
+		if pin := strings.TrimSpace(os.Getenv(versionEnv)); pin != "" {
+			return !app.availableLocally(pin)
+		}
+		if pin, _, ok := findProjectPin(); ok {
+			return !app.availableLocally(pin)
+		}
 	}
 
-	// Make sure local directories exist
-	ensureDirectories()
+	return false
+}
 
-	// Load remote data
-	{
-		var err error
-		// Fetch remote index
-		index, err := FetchIndex()
-		if err != nil {
-			panic(err)
-		}
+// Reports whether name refers to a downloaded version or a custom toolchain,
+// without the index. Channels, partial versions and ranges always need the
+// index, since the newest match may not be downloaded.
+func (app *AppState) availableLocally(name string) bool {
+	if isChannel(name) || isFuzzyVersion(name) {
+		return false
+	}
+
+	item, ok := app.itemForPin(name)
+	return ok && (item.Downloaded || item.Custom)
+}
 
-		// Parse remote index items
-		for k, v := range index.Entries {
-			fileEntry := v.GetFileEntryForHost()
-			if fileEntry == nil {
+// Builds the list of items from the indexes, which may be empty, and the
+// local tarballs, extracted versions and custom toolchains. The indexes are
+// ordered by priority, and a version is taken from the first one having it.
+func (app *AppState) loadItems(indexes []SourceIndex) {
+	app.Items = []Item{}
+	app.machFetched = false
+	app.machVersion = nil
+
+	// Parse remote index items
+	for _, index := range indexes {
+		for k, v := range index.Index.Entries {
+			if k == machLatestKey || isNomination(k) {
 				continue
 			}
-			item := Item{}
 
-			versionString := v.Version
-			if versionString == "" {
-				versionString = k
-			} else {
-				item.Master = true
+			item, ok := app.indexItem(k, v)
+			if !ok {
+				continue
 			}
-
-			version, err := ParseVersion(versionString)
-			if err != nil {
-				panic(err)
+			if _, ok := app.GetItemByVersion(item.Version); ok {
+				continue
 			}
-
-			item.Version = *version
-			item.Indexed = true
-			item.RemoteUrl = fileEntry.Tarball
-			item.LocalPath = localTarballPathFromUrl(item.RemoteUrl)
-
+			item.SourceLabel = index.Source.Label
 			app.Items = append(app.Items, item)
 		}
 	}
 
-	// Scan local tarballs
+	// Mach nominates versions of the indexes above, which may be in any of
+	// them, so its entries come last.
+	for _, index := range indexes {
+		app.addMachItems(index.Index)
+	}
+
+	// Add the local versions
 	{
-		dir, err := os.ReadDir(localDirPath("tarballs"))
-		if err != nil {
-			panic(err)
-		}
-
-		for _, entry := range dir {
-			name := entry.Name()
-			if path.Ext(name) == ".xz" {
-				sp := strings.Split(name, ".")
-				name = strings.Join(sp[0:len(sp)-2], ".")
-				sp = strings.Split(name, "-")
-				// ostag := sp[1]
-				// archtag := sp[2]
-				versionTag := strings.Join(sp[3:], "-")
-
-				version, err := ParseVersion(versionTag)
-				if err != nil {
-					panic(err)
-				}
+		state := app.loadLocalState()
+		for name, v := range state.Versions {
+			version, err := ParseVersion(v.Version)
+			if err != nil || !isHostTarball(v.Tarball) {
+				continue
+			}
+
+			item, ok := app.GetItemByVersion(*version)
+			if !ok {
+				app.Items = append(app.Items, Item{Version: *version})
+				item = &app.Items[len(app.Items)-1]
+			}
 
-				// fmt.Printf("%s, %s, %+v\n", ostag, archtag, *version)
-
-				if item, ok := app.GetItemByVersion(*version); ok {
-					item.Downloaded = true
-					item.LocalPath = localDirPath("tarballs", entry.Name())
-				} else {
-					item := Item{}
-					item.Downloaded = true
-					item.Indexed = false
-					item.LocalPath = localDirPath("tarballs", entry.Name())
-					item.Version = *version
-					app.Items = append(app.Items, item)
+			// Only removed while no other instance may be reading it.
+			if info, err := os.Stat(v.Tarball); err == nil && v.Dir == "" && item.hasIncompleteTarball(info) {
+				if app.holdsExclusiveLock() {
+					logger.infof("Removing incomplete tarball %s\n", path.Base(v.Tarball))
+					os.Remove(v.Tarball)
+					app.recordRemoved(item, true)
 				}
+				continue
+			}
+
+			item.Downloaded = true
+			item.LocalPath = v.Tarball
+			if item.Size == 0 {
+				item.Size = v.Size
+			}
+			// Without the index, the shasum recorded when downloading is
+			// what the tarball is verified against.
+			if item.Shasum == "" {
+				item.Shasum = v.Shasum
 			}
+			item.Current = name == state.Active
 		}
 	}
 
-	// look for current zig
+	// Load custom toolchains
 	{
-		dir, err := os.ReadDir(localDirPath("current"))
+		err := app.loadCustomToolchains()
 		if err != nil {
-			panic(err)
-		}
-
-		if len(dir) > 0 {
-			for _, e := range dir {
-				if strings.HasPrefix(e.Name(), "zig") && e.IsDir() {
-					name := e.Name()
-					sp := strings.Split(name, "-")
-					// ostag := sp[1]
-					// archtag := sp[2]
-					versionTag := strings.Join(sp[3:], "-")
-
-					version, err := ParseVersion(versionTag)
-					if err != nil {
-						panic(err)
-					}
-
-					if item, ok := app.GetItemByVersion(*version); ok {
-						item.Current = true
-					} else {
-						panic("current version is not downloaded!")
-					}
-					break
-				}
-			}
+			app.fail(err)
 		}
 	}
 
@@ -665,62 +1132,494 @@ func (app *AppState) run() {
 			return app.Items[i].Version.moreThan(app.Items[j].Version)
 		})
 	}
+}
+
+func (app *AppState) run() {
+
+	if len(os.Args) < 2 {
+        printUsageAndExit()
+	}
+
+	if os.Args[1] == "help" || os.Args[1] == "--help" || os.Args[1] == "-h" {
+		commandHelp(os.Args[2:])
+	}
+
+	if os.Args[1] == "--version" {
+		commandToolVersion()
+		return
+	}
+
+	spec, ok := findCommand(os.Args[1])
+	if !ok {
+		usageError(fmt.Sprintf("Unknown command %s.", os.Args[1]), "")
+	}
+	command := spec.Id
+	args := spec.parseArgs(os.Args[2:])
+	if args.Has("help") {
+		spec.printUsageAndExit()
+	}
+	app.Args = args
+	logger.setLevelFromArgs(args)
+
+	if !isHostSupported() || (isTermux() && !isTermuxArchSupported()) {
+		app.fail(&UnsupportedHostError{Os: runtime.GOOS, Arch: runtime.GOARCH})
+	}
+	if _, err := os.UserHomeDir(); err != nil {
+		app.fail(err)
+	}
+
+	// Make sure local directories exist
+	if err := ensureDirectories(); err != nil {
+		app.fail(err)
+	}
+
+	// Only one instance may change the local state at a time. Leftovers of
+	// interrupted downloads can only be told apart from running ones with the
+	// lock held exclusively.
+	lock, err := acquireLock(commandIsMutating(command) && !args.Has("dry-run"))
+	if err != nil {
+		app.fail(fmt.Errorf("failed to lock %s: %w", lockPath(), err))
+	}
+	app.lock = lock
+	if lock.exclusive {
+		cleanupPartialArtifacts()
+	}
+
+	// Load config
+	{
+		config, err := LoadConfig()
+		if err != nil {
+			app.fail(fmt.Errorf("failed to load config: %w", err))
+		}
+		app.Config = config
+		if mode, ok := args.Value("color"); ok {
+			if !isValidColorMode(mode) {
+				usageError("Invalid color mode, expected auto, always or never.", spec.Name)
+			}
+			app.Config.Color = mode
+		}
+		applyColorMode(app.Config.Color)
+		app.Notify = config.Notify || args.Has("notify")
+		app.AssumeYes = nonInteractiveRequested(args)
+		app.Json = args.Has("json")
+		app.DryRun = args.Has("dry-run")
+		// pin takes --format for the file it writes instead.
+		if format, ok := args.Value("format"); ok && command != CommandPin {
+			if app.Format, err = parseFormat(format); err != nil {
+				usageError(fmt.Sprintf("Invalid format: %s.", err), spec.Name)
+			}
+		}
+		app.LinkMode = config.LinkMode
+		app.NoNetwork = noNetworkRequested(args)
+		app.Offline = offlineRequested(args)
+		if app.Offline {
+			app.NoNetwork = true
+		}
+		app.ProgressFormat, _ = args.Value("progress")
+		app.OverridePolicy = args.Has("override-policy")
+		app.Force = args.Has("force")
+		if args.Has("stream") {
+			app.Config.StreamExtract = true
+		}
+		if args.Has("keep-quarantine") {
+			app.Config.KeepQuarantine = true
+		}
+		if retries, ok := args.Value("retries"); ok {
+			n, err := strconv.Atoi(retries)
+			if err != nil || n < 1 {
+				usageError("Invalid number of retries.", spec.Name)
+			}
+			app.Config.Retries = n
+		}
+		if app.ProgressFormat != "" && app.ProgressFormat != ProgressFormatJson {
+			usageError("Invalid progress format, expected json.", spec.Name)
+		}
+		if value := os.Getenv("ZIG_TOOLCHAIN_REQUEST_TIMEOUT"); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				app.fail(fmt.Errorf("invalid duration in ZIG_TOOLCHAIN_REQUEST_TIMEOUT: %w", err))
+			}
+			app.Config.RequestTimeout.Duration = d
+		}
+		for _, flag := range []string{"timeout", "request-timeout"} {
+			value, ok := args.Value(flag)
+			if !ok {
+				continue
+			}
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				usageError(fmt.Sprintf("Invalid duration for --%s.", flag), spec.Name)
+			}
+			if flag == "timeout" {
+				app.Config.Timeout.Duration = d
+			} else {
+				app.Config.RequestTimeout.Duration = d
+			}
+		}
+		app.setupContext(app.Config.Timeout.Duration)
+		if concurrency, ok := args.Value("concurrency"); ok {
+			n, err := strconv.Atoi(concurrency)
+			if err != nil || n < 1 {
+				usageError("Invalid concurrency.", spec.Name)
+			}
+			app.Config.Concurrency = n
+		}
+		if proxy, ok := args.Value("proxy"); ok {
+			app.Config.Network.Proxy = proxy
+		}
+		if indexUrl := os.Getenv("ZIG_TOOLCHAIN_INDEX_URL"); indexUrl != "" {
+			app.Config.IndexUrl = indexUrl
+		}
+		if indexUrl, ok := args.Value("index-url"); ok {
+			app.Config.IndexUrl = indexUrl
+		}
+		if indexFile := os.Getenv("ZIG_TOOLCHAIN_INDEX_FILE"); indexFile != "" {
+			app.IndexFile = indexFile
+		}
+		if indexFile, ok := args.Value("index-file"); ok {
+			app.IndexFile = indexFile
+		}
+		if caCert, ok := args.Value("cacert"); ok {
+			app.Config.Network.CaCert = caCert
+		}
+		if args.Has("insecure") {
+			app.Config.Network.Insecure = true
+		}
+		if args.Has("ipv4") {
+			app.Config.Network.IpVersion = "4"
+		} else if args.Has("ipv6") {
+			app.Config.Network.IpVersion = "6"
+		}
+	}
+
+	// Prompts and editors call current and which often, so they skip building
+	// the items.
+	if command == CommandCurrent && !args.Has("explain") {
+		app.commandCurrent()
+		return
+	} else if command == CommandWhich {
+		app.commandWhich(args.Has("lib"))
+		return
+	}
+
+	// Local operations never touch the index, so they work without the
+	// network. Only when the command needs remote data are the items built
+	// again, this time with the index.
+	app.loadItems(nil)
+	if app.commandNeedsIndex(command) {
+		app.Indexes = app.loadIndexes(command)
+		app.loadItems(app.Indexes)
+	}
 
 	switch command {
 	case CommandList:
-		app.commandListRemote()
+		app.commandListRemote(args.Has("platforms"), listFilterFromArgs(args))
+		app.prefetchMaster()
 	case CommandShow:
 		app.commandListLocal()
 	case CommandDownload:
 
-		if len(os.Args) < 3 {
-			fmt.Printf("USAGE: zig-toolchain download [VERSION]\n\n")
-			os.Exit(0)
+		if len(args.Positional) < 1 {
+			spec.printUsageAndExit()
 		}
 
-		if os.Args[2] == "master" {
+		if target, ok := args.Value("target"); ok {
+			app.commandDownloadTarget(args.Positional[0], target)
+		} else if args.Has(ArchiveSource) {
+			app.commandDownloadSource(args.Positional[0], ArchiveSource)
+		} else if args.Has(ArchiveBootstrap) {
+			app.commandDownloadSource(args.Positional[0], ArchiveBootstrap)
+		} else if len(args.Positional) > 1 {
+			app.commandDownloadQueue(args.Positional, args.Has("parallel"))
+		} else if args.Positional[0] == "master" {
 			app.commandDownloadMaster()
+		} else if item, ok := app.resolveName(args.Positional[0]); ok {
+			app.commandDownloadItem(item)
 		} else {
 			var v *Version
 			var err error
-			if v, err = ParseVersion(os.Args[2]); err != nil {
-				fmt.Printf("Invalid version!\n")
-				os.Exit(1)
+			if v, err = ParseVersion(args.Positional[0]); err != nil {
+				app.fail(&VersionNotFoundError{Name: args.Positional[0]})
 			}
 			app.commandDownloadVersion(*v)
 		}
 
-	case CommandActivate:
+	case CommandCurrent:
+		app.commandExplainCurrent()
 
-		if len(os.Args) < 3 {
-			fmt.Printf("USAGE: zig-toolchain activate [VERSION]\n\n")
-			os.Exit(0)
+	case CommandActivate, CommandDefault:
+		if command == CommandDefault && len(args.Positional) == 0 {
+			app.commandCurrent()
+			return
+		}
+
+		if mode, ok := args.Value("link-mode"); ok {
+			if !isValidLinkMode(mode) {
+				usageError("Invalid link mode, expected symlink, hardlink, copy, shim or auto.", spec.Name)
+			}
+			app.LinkMode = mode
+		}
+		app.DeleteTarball = args.Has("delete-tarball")
+
+		if len(args.Positional) < 1 {
+			spec.printUsageAndExit()
 		}
 
-		if os.Args[2] == "master" {
+		if args.Has("local") && args.Has("global") {
+			usageError("--local and --global can't be used together.", spec.Name)
+		}
+
+		if args.Has("local") {
+			app.commandActivateLocal(args.Positional[0])
+		} else if args.Positional[0] == "master" {
 			app.commandActivateMaster()
+		} else if item, ok := app.resolveName(args.Positional[0]); ok {
+			app.commandActivateItem(item)
 		} else {
 			var v *Version
 			var err error
-			if v, err = ParseVersion(os.Args[2]); err != nil {
-				fmt.Printf("Invalid version!\n")
-				os.Exit(1)
+			if v, err = ParseVersion(args.Positional[0]); err != nil {
+				app.fail(&VersionNotFoundError{Name: args.Positional[0]})
 			}
 			app.commandActivateVersion(*v)
 		}
 
     case CommandDeactivate:
-        err := os.Remove(zigBinPath())
-        if err != nil {
-            panic(err)
-        }
-        os.RemoveAll(localDirPath("current"))
-        ensureDirectories()
+        app.commandDeactivate()
+
+	case CommandPin:
+		if len(args.Positional) < 1 {
+			spec.printUsageAndExit()
+		}
+
+		format, ok := args.Value("format")
+		if !ok {
+			format = PinFormatZigVersion
+		}
+		if !isValidPinFormat(format) {
+			usageError(fmt.Sprintf("Unknown pin format %s, expected %s or %s.", format, PinFormatZigVersion, PinFormatToolVersions), spec.Name)
+		}
+
+		app.commandPin(args.Positional[0], format)
+
+	case CommandWhy:
+		if len(args.Positional) < 1 {
+			spec.printUsageAndExit()
+		}
+
+		item, ok := app.itemForPin(args.Positional[0])
+		if !ok {
+			app.fail(&VersionNotFoundError{Name: args.Positional[0]})
+		}
+		app.commandWhy(item.Version)
+
+	case CommandGc:
+		app.commandGc(args.Has("tarballs"))
+
+	case CommandSmokeTest:
+		var item *Item
+		var ok bool
+		if len(args.Positional) < 1 {
+			item, ok = app.GetCurrentActiveItem()
+			if !ok {
+				logger.errorf("No active version!\n")
+				os.Exit(1)
+			}
+		} else if item, ok = app.itemForPin(args.Positional[0]); !ok {
+			app.fail(&VersionNotFoundError{Name: args.Positional[0]})
+		}
+
+		app.commandSmokeTest(item)
+
+	case CommandBisect:
+		goodString, hasGood := args.Value("good")
+		badString, hasBad := args.Value("bad")
+		if !hasGood || !hasBad || len(args.Rest) == 0 {
+			spec.printUsageAndExit()
+		}
+
+		good, err := ParseVersion(goodString)
+		if err != nil {
+			app.fail(&VersionNotFoundError{Name: goodString})
+		}
+		bad, err := ParseVersion(badString)
+		if err != nil {
+			app.fail(&VersionNotFoundError{Name: badString})
+		}
+
+		app.commandBisect(*good, *bad, args.Rest)
+
+	case CommandAsdf:
+		app.commandAsdf(os.Args[2:])
+
+	case CommandExec:
+		if len(args.Rest) == 0 {
+			spec.printUsageAndExit()
+		}
+
+		if args.Has("strict") {
+			app.Config.AutoInstall = false
+		}
+
+		version := ""
+		if len(args.Positional) == 1 {
+			version = args.Positional[0]
+		} else if len(args.Positional) > 1 {
+			spec.printUsageAndExit()
+		}
+
+		logger.stderr = true
+		app.commandExec(version, args.Rest)
+
+	case CommandLink:
+		if name, ok := args.Value("remove"); ok {
+			app.commandUnlink(name)
+		} else if len(args.Positional) == 2 {
+			app.commandLink(args.Positional[0], args.Positional[1])
+		} else {
+			spec.printUsageAndExit()
+		}
+
+	case CommandInstall:
+		file, ok := args.Value("file")
+		if !ok {
+			spec.printUsageAndExit()
+		}
+
+		app.DeleteTarball = args.Has("delete-tarball")
+		app.commandInstallFile(file, args.Has("activate"))
+
+	case CommandDedupe:
+		app.commandDedupe()
+
+	case CommandPrune:
+		for flag, value := range map[string]*int{"keep-dev": &app.Config.Prune.KeepDev, "keep-days": &app.Config.Prune.KeepUsedWithinDays} {
+			if s, ok := args.Value(flag); ok {
+				n, err := strconv.Atoi(s)
+				if err != nil || n < 0 {
+					usageError(fmt.Sprintf("Invalid value for --%s.", flag), spec.Name)
+				}
+				*value = n
+			}
+		}
+		app.commandPrune()
+
+	case CommandUpgrade:
+		if len(args.Positional) != 1 {
+			spec.printUsageAndExit()
+		}
+
+		app.commandUpgrade(args.Positional[0], args.Has("remove-old"))
+
+	case CommandOutdated:
+		app.commandOutdated(app.Json)
+
+	case CommandRollback:
+		app.commandRollback()
+
+	case CommandAlias:
+		if name, ok := args.Value("remove"); ok {
+			app.commandUnalias(name)
+		} else if len(args.Positional) == 2 {
+			app.commandAlias(args.Positional[0], args.Positional[1])
+		} else if len(args.Positional) == 0 {
+			app.commandListAliases()
+		} else {
+			spec.printUsageAndExit()
+		}
+
+	case CommandAdopt:
+		if len(args.Positional) != 1 {
+			spec.printUsageAndExit()
+		}
+
+		name, ok := args.Value("name")
+		if !ok {
+			name = filepath.Base(filepath.Clean(args.Positional[0]))
+		}
+		app.commandLink(name, args.Positional[0])
+
+	case CommandUpdate:
+		app.commandUpdate()
+
+	case CommandIndex:
+		if len(args.Positional) != 1 || args.Positional[0] != "export" {
+			spec.printUsageAndExit()
+		}
+
+		app.commandIndexExport()
+
+	case CommandInfo:
+		if len(args.Positional) < 1 {
+			spec.printUsageAndExit()
+		}
+
+		app.commandInfo(args.Positional[0])
+
+	case CommandSearch:
+		if len(args.Positional) != 1 {
+			spec.printUsageAndExit()
+		}
+
+		app.commandSearch(args.Positional[0])
+
+	case CommandEnv:
+		shell, _ := args.Value("shell")
+		if shell != "" && !isEnvShell(shellName(shell)) {
+			usageError(fmt.Sprintf("Unknown shell %s, expected one of %s.", shell, strings.Join(envShells, ", ")), spec.Name)
+		}
+
+		version := ""
+		if len(args.Positional) == 1 {
+			version = args.Positional[0]
+		} else if len(args.Positional) > 1 {
+			spec.printUsageAndExit()
+		}
+
+		logger.stderr = true
+		app.commandEnv(version, shellName(shell))
+
+	case CommandShell:
+		if len(args.Positional) != 1 {
+			spec.printUsageAndExit()
+		}
+
+		if args.Has("print") {
+			logger.stderr = true
+		}
+
+		app.commandShell(args.Positional[0], args.Has("print"))
+
+	case CommandCompletion:
+		if which, ok := args.Value("versions"); ok {
+			app.commandCompleteVersions(which == "local")
+		} else if len(args.Positional) == 1 {
+			commandCompletion(args.Positional[0])
+		} else {
+			spec.printUsageAndExit()
+		}
+
+	case CommandResolve:
+		if len(args.Positional) < 1 {
+			spec.printUsageAndExit()
+		}
+
+		app.commandResolve(args.Positional[0])
+
+	case CommandRemove:
+		if args.Has("all-dev") {
+			app.commandRemoveAllDev()
+		} else if len(args.Positional) == 1 {
+			app.commandRemove(args.Positional[0])
+		} else {
+			spec.printUsageAndExit()
+		}
 	}
 }
 
 func main() {
 	app := NewAppState()
+	defer app.recoverPanic()
 	app.run()
 }
 
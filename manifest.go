@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+const (
+	ManifestFile = "manifest.json"
+)
+
+// Record of where an extracted toolchain came from, written next to its
+// files in versions/.
+type Manifest struct {
+	Version     string    `json:"version"`
+	Url         string    `json:"url,omitempty"`
+	Tarball     string    `json:"tarball"`
+	Shasum      string    `json:"shasum"`
+	Date        string    `json:"date,omitempty"`
+	ExtractedAt time.Time `json:"extracted_at"`
+	Files       []string  `json:"files"`
+}
+
+// Writes the manifest of item's toolchain, extracted at dir.
+func writeManifest(item *Item, dir string) error {
+	manifest := Manifest{
+		Version:     item.Version.FullString(),
+		Url:         item.RemoteUrl,
+		Tarball:     path.Base(item.LocalPath),
+		Shasum:      item.Shasum,
+		Date:        item.Date,
+		ExtractedAt: time.Now().UTC(),
+		Files:       []string{},
+	}
+
+	if manifest.Shasum == "" {
+		sum, err := fileSha256(item.LocalPath)
+		if err != nil {
+			return err
+		}
+		manifest.Shasum = sum
+	}
+
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, filepath.ToSlash(rel))
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path.Join(dir, ManifestFile), data, 0644)
+}
+
+// Reads the manifest of the toolchain extracted at dir.
+func readManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(path.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{}
+	if err = json.Unmarshal(data, manifest); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// Returns the version of the toolchain extracted into the directory name in
+// versions/, from its manifest, or else from the zig binary itself, or as a
+// last resort from the directory name, e.g. `zig-linux-x86_64-0.11.0`.
+func extractedVersion(name string) (*Version, error) {
+	dir := localDirPath("versions", name)
+	if manifest, err := readManifest(dir); err == nil {
+		return ParseVersion(manifest.Version)
+	}
+
+	if isExtracted(dir) {
+		if version, err := queryZigVersion(dir); err == nil {
+			return version, nil
+		}
+	}
+
+	if _, version, err := parseArtifactName(name); err == nil {
+		return version, nil
+	}
+
+	return ParseVersion(name)
+}
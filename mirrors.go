@@ -0,0 +1,48 @@
+package main
+
+import (
+	"path"
+	"strings"
+)
+
+// Returns the URLs to try, in order, to download the tarball of item: each
+// configured mirror, the URL from the index as rewritten by the rewrite
+// rules, and finally the URL from the index as is.
+func (app *AppState) downloadUrls(item *Item) []string {
+	urls := []string{}
+	filename := path.Base(item.LocalPath)
+
+	for _, mirror := range app.Config.Mirrors {
+		urls = append(urls, strings.TrimSuffix(mirror, "/")+"/"+filename)
+	}
+
+	urls = append(urls, item.RemoteUrl)
+	if item.OriginalUrl != "" && item.OriginalUrl != item.RemoteUrl {
+		urls = append(urls, item.OriginalUrl)
+	}
+
+	return urls
+}
+
+// Downloads the tarball of item, falling back to the next source when one
+// fails. Whatever the source, the tarball is verified against the shasum
+// from the official index.
+func (app *AppState) downloadFromSources(item *Item) error {
+	var err error
+	for i, url := range app.downloadUrls(item) {
+		if i > 0 {
+			logger.warnf("%s\nFalling back to %s\n", err, url)
+		}
+
+		candidate := *item
+		candidate.RemoteUrl = url
+		err = app.withRetry("Download", func() error {
+			return app.downloadTarball(candidate)
+		})
+		if err == nil || app.context().Err() != nil {
+			return err
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// Looks up the login and password for host in ~/.netrc (or $NETRC), falling
+// back to a `default` entry if useDefault is set.
+func netrcCredentials(host string, useDefault bool) (*Credentials, bool) {
+	p := os.Getenv("NETRC")
+	if p == "" {
+		p = homeDirPath(".netrc")
+	}
+
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, false
+	}
+
+	var match, fallback *Credentials
+	var current *Credentials
+	tokens := strings.Fields(string(data))
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "machine":
+			current = nil
+			if i+1 < len(tokens) {
+				i++
+				if tokens[i] == host && match == nil {
+					match = &Credentials{}
+					current = match
+				}
+			}
+		case "default":
+			current = nil
+			if fallback == nil {
+				fallback = &Credentials{}
+				current = fallback
+			}
+		case "login", "password", "account":
+			if i+1 >= len(tokens) {
+				break
+			}
+			i++
+			if current == nil {
+				continue
+			}
+			if tokens[i-1] == "login" {
+				current.Username = tokens[i]
+			} else if tokens[i-1] == "password" {
+				current.Password = tokens[i]
+			}
+		case "macdef":
+			// Macro definitions run until an empty line, which Fields has
+			// already lost. They're rare enough in practice to ignore.
+			current = nil
+		}
+	}
+
+	if match != nil && match.Password != "" {
+		return match, true
+	}
+	if useDefault && fallback != nil && fallback.Password != "" {
+		return fallback, true
+	}
+
+	return nil, false
+}
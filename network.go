@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+)
+
+// Reports whether network access is forbidden, via --no-network or
+// ZIG_TOOLCHAIN_NO_NETWORK=1. Unlike falling back to local data, any
+// operation that needs the network then fails hard, which is useful to assert
+// that hermetic builds never touch the network.
+func noNetworkRequested(args *Args) bool {
+	return args.Has("no-network") || os.Getenv("ZIG_TOOLCHAIN_NO_NETWORK") == "1"
+}
+
+// Reports whether --offline or ZIG_TOOLCHAIN_OFFLINE=1 was given. Offline,
+// nothing is fetched, and the cached index is used however old it is.
+func offlineRequested(args *Args) bool {
+	return args.Has("offline") || os.Getenv("ZIG_TOOLCHAIN_OFFLINE") == "1"
+}
+
+// Reports whether err means the network can't be reached at all, as opposed
+// to a server answering with an error.
+func isUnreachable(err error) bool {
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	var netErr net.Error
+	return errors.As(err, &dnsErr) || errors.As(err, &opErr) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// Switches to offline mode after the network turned out to be unreachable.
+func (app *AppState) goOffline(err error) {
+	logger.warnf("The network is unreachable, working offline (%s)\n", err)
+	app.Offline = true
+	app.NoNetwork = true
+}
+
+// Returns an error if network access is forbidden.
+func (app *AppState) networkError(reason string) error {
+	if app.Offline {
+		return fmt.Errorf("network access is required to %s, but zig-toolchain is offline", reason)
+	}
+	if app.NoNetwork {
+		return fmt.Errorf("network access is required to %s, but it is disabled with --no-network", reason)
+	}
+
+	return nil
+}
+
+// Exits with an error if network access is forbidden.
+func (app *AppState) requireNetwork(reason string) {
+	if err := app.networkError(reason); err != nil {
+		app.fail(err)
+	}
+}
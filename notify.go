@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Shows a desktop notification if notifications are enabled. Failures are
+// ignored, notifications are best effort only.
+func (app *AppState) notify(message string) {
+	if !app.Notify {
+		return
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux":
+		cmd = exec.Command("notify-send", "zig-toolchain", message)
+	case "darwin":
+		script := fmt.Sprintf("display notification %q with title \"zig-toolchain\"", message)
+		cmd = exec.Command("osascript", "-e", script)
+	case "windows":
+		script := strings.Join([]string{
+			"Add-Type -AssemblyName System.Windows.Forms",
+			"$n = New-Object System.Windows.Forms.NotifyIcon",
+			"$n.Icon = [System.Drawing.SystemIcons]::Information",
+			"$n.Visible = $true",
+			fmt.Sprintf("$n.ShowBalloonTip(5000, 'zig-toolchain', '%s', 'Info')", strings.ReplaceAll(message, "'", "''")),
+			"Start-Sleep -Seconds 5",
+			"$n.Dispose()",
+		}, "; ")
+		cmd = exec.Command("powershell", "-NoProfile", "-Command", script)
+	default:
+		return
+	}
+
+	cmd.Start()
+}
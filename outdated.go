@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+type OutdatedReport struct {
+	Active       string `json:"active"`
+	LatestStable string `json:"latest_stable,omitempty"`
+	LatestMaster string `json:"latest_master,omitempty"`
+	BehindStable bool   `json:"behind_stable"`
+	BehindMaster bool   `json:"behind_master"`
+}
+
+// Reports whether the active version is behind the latest stable release
+// and the latest master build from the index.
+func (app *AppState) commandOutdated(asJson bool) {
+	active, ok := app.GetCurrentActiveItem()
+	if !ok {
+		fmt.Printf("No active version!\n")
+		os.Exit(1)
+	}
+
+	report := OutdatedReport{Active: active.Version.FullString()}
+	if stable, ok := app.latestStableItem(); ok {
+		report.LatestStable = stable.Version.FullString()
+		report.BehindStable = stable.Version.moreThan(active.Version)
+	}
+	if master, ok := app.masterItem(); ok {
+		report.LatestMaster = master.Version.FullString()
+		report.BehindMaster = master.Version.moreThan(active.Version)
+	}
+
+	if asJson {
+		data, err := json.Marshal(report)
+		if err != nil {
+			app.fail(err)
+		}
+		fmt.Printf("%s\n", data)
+		return
+	}
+
+	fmt.Printf("Active: %s\n", active.Name())
+	if report.LatestStable != "" {
+		fmt.Printf("Stable: %s%s\n", report.LatestStable, outdatedMarker(report.BehindStable))
+	}
+	if report.LatestMaster != "" {
+		fmt.Printf("Master: %s%s\n", report.LatestMaster, outdatedMarker(report.BehindMaster))
+	}
+}
+
+func outdatedMarker(behind bool) string {
+	if behind {
+		return " (newer)"
+	}
+
+	return ""
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Organization policy restricting which versions may be downloaded or
+// activated.
+type Policy struct {
+	// Oldest allowed version.
+	MinVersion string `json:"min_version"`
+	// Whether dev builds are allowed at all. Defaults to true.
+	AllowDev *bool `json:"allow_dev"`
+	// If not empty, only these versions are allowed.
+	Allow []string `json:"allow"`
+	// Versions that are never allowed.
+	Block []string `json:"block"`
+}
+
+func (p *Policy) validate() error {
+	versions := append([]string{}, p.Allow...)
+	versions = append(versions, p.Block...)
+	if p.MinVersion != "" {
+		versions = append(versions, p.MinVersion)
+	}
+
+	for _, v := range versions {
+		if _, err := ParseVersion(v); err != nil {
+			return fmt.Errorf("invalid version %q in policy", v)
+		}
+	}
+
+	return nil
+}
+
+func versionInList(v Version, list []string) bool {
+	for _, s := range list {
+		if other, err := ParseVersion(s); err == nil && other.equal(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns an error describing why the policy forbids v, if it does.
+func (p *Policy) check(v Version) error {
+	if versionInList(v, p.Block) {
+		return fmt.Errorf("%s is blocked", v.String())
+	}
+
+	if len(p.Allow) > 0 && !versionInList(v, p.Allow) {
+		return fmt.Errorf("%s is not in the list of allowed versions", v.String())
+	}
+
+	if p.AllowDev != nil && !*p.AllowDev && v.Dev {
+		return fmt.Errorf("dev builds are not allowed")
+	}
+
+	if p.MinVersion != "" {
+		min, _ := ParseVersion(p.MinVersion)
+		if v.lessThan(*min) {
+			return fmt.Errorf("versions older than %s are not allowed", min.String())
+		}
+	}
+
+	return nil
+}
+
+type PolicyError struct {
+	Version Version
+	Reason  error
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("zig %s is not allowed by policy: %s (pass --override-policy to ignore the policy)", e.Version.String(), e.Reason)
+}
+
+func (e *PolicyError) Unwrap() error {
+	return e.Reason
+}
+
+// Returns an error if the policy forbids item, unless it was overridden with
+// --override-policy. Custom toolchains are not subject to the policy.
+func (app *AppState) policyError(item *Item) error {
+	if item.Custom || app.OverridePolicy {
+		return nil
+	}
+
+	if err := app.Config.Policy.check(item.Version); err != nil {
+		return &PolicyError{Version: item.Version, Reason: err}
+	}
+
+	return nil
+}
+
+// Exits with an error if the policy forbids item.
+func (app *AppState) enforcePolicy(item *Item) {
+	if err := app.policyError(item); err != nil {
+		app.fail(err)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPolicyError(t *testing.T) {
+	no := false
+	policy := Policy{
+		MinVersion: "0.11.0",
+		AllowDev:   &no,
+		Block:      []string{"0.12.0"},
+	}
+	allowList := Policy{Allow: []string{"0.11.0", "0.13.0"}}
+
+	tests := []struct {
+		policy  Policy
+		version string
+		custom  bool
+		allowed bool
+	}{
+		{policy, "0.11.0", false, true},
+		{policy, "0.13.0", false, true},
+		{policy, "0.10.1", false, false},
+		{policy, "0.12.0", false, false},
+		{policy, "0.14.0-dev.100+abcdef123", false, false},
+		// Custom toolchains aren't subject to the policy.
+		{policy, "0.12.0", true, true},
+		{allowList, "0.11.0", false, true},
+		{allowList, "0.12.0", false, false},
+		{Policy{}, "0.14.0-dev.100+abcdef123", false, true},
+	}
+
+	for _, test := range tests {
+		v, err := ParseVersion(test.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		app := NewAppState()
+		app.Config.Policy = test.policy
+		err = app.policyError(&Item{Version: *v, Custom: test.custom})
+		if allowed := err == nil; allowed != test.allowed {
+			t.Errorf("%s (custom %v): allowed = %v, want %v (%v)", test.version, test.custom, allowed, test.allowed, err)
+		}
+
+		var policyErr *PolicyError
+		if err != nil && !errors.As(err, &policyErr) {
+			t.Errorf("%s: error %v is not a PolicyError", test.version, err)
+		}
+
+		// --override-policy allows everything.
+		app.OverridePolicy = true
+		if err = app.policyError(&Item{Version: *v, Custom: test.custom}); err != nil {
+			t.Errorf("%s: not allowed with --override-policy: %s", test.version, err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+func prefetchLogPath() string {
+	return localDirPath("prefetch.log")
+}
+
+// Starts downloading the latest master in a background process if it isn't
+// downloaded yet, so that a later `activate master` doesn't have to wait.
+func (app *AppState) prefetchMaster() {
+	if !app.Config.PrefetchMaster || app.NoNetwork {
+		return
+	}
+
+	master, ok := app.itemForPin("master")
+	if !ok || master.Downloaded {
+		return
+	}
+
+	if app.policyError(master) != nil {
+		return
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return
+	}
+
+	log, err := os.Create(prefetchLogPath())
+	if err != nil {
+		return
+	}
+	defer log.Close()
+
+	cmd := exec.Command(self, "download", "master", "--yes")
+	cmd.Stdout = log
+	cmd.Stderr = log
+	if err = cmd.Start(); err != nil {
+		return
+	}
+
+	logger.infof("\nPrefetching master %s in the background (log: %s)\n", master.Version.String(), prefetchLogPath())
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-isatty"
+)
+
+const (
+	ProgressFormatJson = "json"
+
+	// Minimum interval between two download_progress events.
+	progressInterval = 250 * time.Millisecond
+)
+
+// Event of the machine readable progress stream enabled with
+// `--progress json`, written to stderr as one JSON object per line.
+type ProgressEvent struct {
+	Event   string `json:"event"`
+	Time    string `json:"time"`
+	Version string `json:"version,omitempty"`
+	Url     string `json:"url,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Bytes   int64  `json:"bytes,omitempty"`
+	Total   int64  `json:"total,omitempty"`
+}
+
+func (app *AppState) emitProgress(event ProgressEvent) {
+	if app.ProgressFormat != ProgressFormatJson {
+		return
+	}
+
+	event.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	os.Stderr.Write(append(data, '\n'))
+}
+
+// Reader calling onProgress with the number of bytes read so far, at most
+// once every progressInterval and once more at EOF.
+type progressReader struct {
+	reader     io.Reader
+	read       int64
+	last       time.Time
+	onProgress func(read int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+
+	if err == io.EOF || time.Since(r.last) >= progressInterval {
+		r.last = time.Now()
+		r.onProgress(r.read)
+	}
+
+	return n, err
+}
+
+const (
+	progressBarWidth = 30
+	// Interval between two progress lines when stdout is not a terminal.
+	progressLineInterval = 5 * time.Second
+)
+
+// Download progress display. On a terminal it redraws a single bar line,
+// otherwise it prints a progress line every progressLineInterval.
+type progressBar struct {
+	total    int64
+	start    time.Time
+	tty      bool
+	lastLine time.Time
+}
+
+// Returns a progress bar for a download of total bytes. Bars are never
+// redrawn in place while several downloads run in parallel.
+func (app *AppState) newProgressBar(total int64) *progressBar {
+	return &progressBar{
+		total:    total,
+		start:    time.Now(),
+		tty:      isatty.IsTerminal(logger.out().Fd()) && !app.ParallelDownloads,
+		lastLine: time.Now(),
+	}
+}
+
+func (b *progressBar) status(read int64) string {
+	elapsed := time.Since(b.start).Seconds()
+	speed := int64(0)
+	if elapsed > 0 {
+		speed = int64(float64(read) / elapsed)
+	}
+
+	if b.total <= 0 {
+		return fmt.Sprintf("%s %s/s", humanSize(read), humanSize(speed))
+	}
+
+	percent := float64(read) / float64(b.total) * 100
+	eta := "?"
+	if speed > 0 {
+		eta = (time.Duration((b.total-read)/speed) * time.Second).String()
+	}
+
+	return fmt.Sprintf("%s / %s (%.0f%%) %s/s ETA %s", humanSize(read), humanSize(b.total), percent, humanSize(speed), eta)
+}
+
+func (b *progressBar) update(read int64) {
+	if !b.tty {
+		if time.Since(b.lastLine) >= progressLineInterval {
+			b.lastLine = time.Now()
+			logger.infof("    %s\n", b.status(read))
+		}
+		return
+	}
+
+	bar := ""
+	if b.total > 0 {
+		filled := int(float64(read) / float64(b.total) * progressBarWidth)
+		if filled > progressBarWidth {
+			filled = progressBarWidth
+		}
+		bar = "[" + strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled) + "] "
+	}
+
+	logger.infof("\r\033[K    %s%s", bar, b.status(read))
+}
+
+func (b *progressBar) finish(read int64) {
+	if b.tty {
+		b.update(read)
+		logger.infof("\n")
+	}
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	ProjectPinFile = ".zig-version"
+)
+
+// Registry of the project directories that pin a toolchain version. It is
+// used to know which downloaded versions are still needed by someone.
+type ProjectRegistry struct {
+	Projects []string `json:"projects"`
+}
+
+func projectRegistryPath() string {
+	return localDirPath("projects.json")
+}
+
+func LoadProjectRegistry() (*ProjectRegistry, error) {
+	registry := &ProjectRegistry{Projects: []string{}}
+
+	data, err := os.ReadFile(projectRegistryPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return registry, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, registry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", projectRegistryPath(), err)
+	}
+
+	return registry, nil
+}
+
+func (r *ProjectRegistry) Save() error {
+	sort.Strings(r.Projects)
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(projectRegistryPath(), data, 0644)
+}
+
+func (r *ProjectRegistry) Has(dir string) bool {
+	for _, p := range r.Projects {
+		if p == dir {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *ProjectRegistry) Add(dir string) {
+	if !r.Has(dir) {
+		r.Projects = append(r.Projects, dir)
+	}
+}
+
+// Remembers the project at dir, whose pin file was used, so that projects
+// pinned by hand or checked out with a pin file are known too. The lock is
+// only taken exclusively for projects not known yet, which is rare, and
+// failing to remember one doesn't stop the command.
+func (app *AppState) registerProject(dir string) {
+	registry, err := LoadProjectRegistry()
+	if err != nil || registry.Has(dir) {
+		return
+	}
+
+	// Another instance may have changed the registry while waiting for the
+	// lock.
+	app.lockExclusively()
+	if registry, err = LoadProjectRegistry(); err != nil {
+		return
+	}
+	registry.Add(dir)
+	if err = registry.Save(); err != nil {
+		logger.debugf("Failed to remember the project %s: %s\n", dir, err)
+	}
+}
+
+// Reads the version pinned by the project at dir, if any, and returns it with
+// the file it comes from: .zig-version, or else .tool-versions or mise.toml.
+func readProjectPin(dir string) (string, string, bool) {
+	file := path.Join(dir, ProjectPinFile)
+	if data, err := os.ReadFile(file); err == nil {
+		if pin := strings.TrimSpace(string(data)); pin != "" {
+			return pin, file, true
+		}
+	}
+
+	return readToolPin(dir)
+}
+
+// Returns the item a pin string refers to, where pin is either a channel, the
+// name of a custom toolchain, an alias or a version string.
+func (app *AppState) itemForPin(pin string) (*Item, bool) {
+	if item, ok := app.itemForName(pin); ok {
+		return item, true
+	}
+
+	v, err := ParseVersion(pin)
+	if err != nil {
+		return nil, false
+	}
+
+	return app.GetItemByVersion(*v)
+}
+
+// Reports whether a registered project pins something only the index can
+// resolve, e.g. a channel like master, a partial version or a range. Without
+// the index, the versions they need could be taken as unused.
+func (app *AppState) projectPinsNeedIndex() bool {
+	registry, err := LoadProjectRegistry()
+	if err != nil {
+		return false
+	}
+
+	for _, dir := range registry.Projects {
+		if pin, _, ok := readProjectPin(dir); ok && !app.availableLocally(pin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the registered projects that still pin the given item. Projects
+// whose directory or pin file is gone are dropped from the registry, which
+// is only saved with the lock held exclusively, i.e. not by read-only
+// commands like why.
+func (app *AppState) projectsReferencing(registry *ProjectRegistry, item *Item) []string {
+	result := []string{}
+	alive := []string{}
+
+	for _, dir := range registry.Projects {
+		pin, _, ok := readProjectPin(dir)
+		if !ok {
+			continue
+		}
+		alive = append(alive, dir)
+
+		if pinned, ok := app.itemForPin(pin); ok && pinned == item {
+			result = append(result, dir)
+		}
+	}
+
+	if len(alive) != len(registry.Projects) && app.holdsExclusiveLock() {
+		registry.Projects = alive
+		registry.Save()
+	}
+
+	return result
+}
+
+func (app *AppState) commandPin(pin string, format string) {
+	if _, ok := app.itemForPin(pin); !ok {
+		app.fail(&VersionNotFoundError{Name: pin})
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		app.fail(err)
+	}
+
+	if format == PinFormatToolVersions {
+		_, err = writeToolVersionsPin(dir, pin)
+	} else {
+		err = os.WriteFile(path.Join(dir, ProjectPinFile), []byte(pin+"\n"), 0644)
+	}
+	if err != nil {
+		app.fail(err)
+	}
+
+	registry, err := LoadProjectRegistry()
+	if err != nil {
+		app.fail(err)
+	}
+	registry.Add(dir)
+	if err = registry.Save(); err != nil {
+		app.fail(err)
+	}
+
+	logger.infof("Pinned %s to %s\n", dir, pin)
+}
+
+// Pins the project in the working directory to pin, downloading the version
+// it refers to if needed, and leaves the global default alone.
+func (app *AppState) commandActivateLocal(pin string) {
+	item, ok := app.itemForPin(pin)
+	if !ok {
+		app.fail(&VersionNotFoundError{Name: pin})
+	}
+
+	if app.DryRun {
+		fmt.Printf("Would write %s to %s\n", pin, ProjectPinFile)
+		if !item.Downloaded && !item.Custom {
+			fmt.Printf("Would download %s to %s (%s)\n", item.RemoteUrl, item.LocalPath, humanSize(item.Size))
+		}
+		return
+	}
+
+	if !item.Downloaded && !item.Custom {
+		app.commandDownloadItem(item)
+	}
+	app.commandPin(pin, PinFormatZigVersion)
+}
+
+func (app *AppState) commandWhy(v Version) {
+	item, ok := app.GetItemByVersion(v)
+	if !ok {
+		app.fail(&VersionNotFoundError{Name: v.String()})
+	}
+
+	registry, err := LoadProjectRegistry()
+	if err != nil {
+		app.fail(err)
+	}
+
+	if item.Current {
+		fmt.Printf("%s is the active version.\n", item.Version.String())
+	}
+
+	projects := app.projectsReferencing(registry, item)
+	if len(projects) == 0 {
+		if !item.Current {
+			fmt.Printf("%s is not needed by any known project.\n", item.Version.String())
+		}
+		return
+	}
+
+	fmt.Printf("%s is pinned by:\n\n", item.Version.String())
+	for _, p := range projects {
+		fmt.Printf("==> %s\n", p)
+	}
+}
+
+// Returns the downloaded versions that are neither active nor pinned by a
+// project in registry.
+func (app *AppState) gcCandidates(registry *ProjectRegistry) []*Item {
+	candidates := []*Item{}
+	for i := 0; i < len(app.Items); i++ {
+		item := &app.Items[i]
+		if !item.Downloaded || item.Custom {
+			continue
+		}
+
+		if item.Current {
+			logger.infof("Keeping %s (active)\n", item.Version.String())
+			continue
+		}
+
+		if projects := app.projectsReferencing(registry, item); len(projects) > 0 {
+			logger.infof("Keeping %s (pinned by %s)\n", item.Version.String(), strings.Join(projects, ", "))
+			continue
+		}
+
+		candidates = append(candidates, item)
+	}
+
+	return candidates
+}
+
+// Removes the downloaded versions that are neither active nor pinned by a
+// known project, then the tarballs of the versions left that are already
+// extracted. With tarballsOnly, no versions are removed.
+func (app *AppState) commandGc(tarballsOnly bool) {
+	registry, err := LoadProjectRegistry()
+	if err != nil {
+		app.fail(err)
+	}
+
+	candidates := []*Item{}
+	if !tarballsOnly {
+		candidates = app.gcCandidates(registry)
+	}
+
+	if app.DryRun {
+		app.printGcPlan(candidates)
+		return
+	}
+
+	app.confirmRemoval(candidates)
+
+	removed := 0
+	for _, item := range candidates {
+		logger.infof("Removing %s...", item.Version.String())
+		err = os.Remove(item.LocalPath)
+		if err != nil && !os.IsNotExist(err) {
+			app.fail(err)
+		}
+		if err = os.RemoveAll(extractedDirForItem(item)); err != nil {
+			app.fail(err)
+		}
+		item.Downloaded = false
+		app.recordRemoved(item, true)
+		removed++
+		logger.infof("Done!\n")
+	}
+
+	tarballs := 0
+	for i := 0; i < len(app.Items); i++ {
+		item := &app.Items[i]
+		if !item.Downloaded || item.Custom {
+			continue
+		}
+
+		deleted, err := app.removeExtractedTarball(item)
+		if err != nil {
+			app.fail(err)
+		}
+		if deleted {
+			logger.infof("Deleted %s\n", path.Base(item.LocalPath))
+			tarballs++
+		}
+	}
+
+	if err = pruneStore(); err != nil {
+		app.fail(err)
+	}
+
+	if !tarballsOnly {
+		logger.infof("Removed %d version(s).\n", removed)
+	}
+	logger.infof("Deleted %d tarball(s) of extracted versions.\n", tarballs)
+}
+
+// Prints what gc would remove: the candidates, and the tarballs of the
+// extracted versions left.
+func (app *AppState) printGcPlan(candidates []*Item) {
+	removed := map[*Item]bool{}
+	freed := int64(0)
+	for _, item := range candidates {
+		removed[item] = true
+		freed += app.printRemovalPlan(item)
+	}
+
+	tarballs := 0
+	for i := 0; i < len(app.Items); i++ {
+		item := &app.Items[i]
+		if !item.Downloaded || item.Custom || removed[item] || !isExtracted(extractedDirForItem(item)) {
+			continue
+		}
+
+		if size := fileSize(item.LocalPath); size > 0 {
+			fmt.Printf("Would delete %s (%s)\n", item.LocalPath, humanSize(size))
+			freed += size
+			tarballs++
+		}
+	}
+
+	fmt.Printf("Would remove %d version(s) and %d tarball(s) of extracted versions, freeing %s.\n", len(candidates), tarballs, humanSize(freed))
+}
+
+// Deletes the tarball of item if the version is extracted, as it's no longer
+// needed to activate it. Reports whether there was a tarball to delete.
+func (app *AppState) removeExtractedTarball(item *Item) (bool, error) {
+	if !isExtracted(extractedDirForItem(item)) {
+		return false, nil
+	}
+
+	err := os.Remove(item.LocalPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	app.recordRemoved(item, false)
+	return true, nil
+}
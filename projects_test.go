@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGcCandidatesKeepsActiveAndPinnedVersions(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	pin := func(version string) string {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, ProjectPinFile), []byte(version+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+	exact, partial := pin("0.13.0"), pin("0.11")
+	gone := filepath.Join(t.TempDir(), "gone")
+
+	app := NewAppState()
+	for _, item := range []struct {
+		version    string
+		downloaded bool
+		current    bool
+	}{
+		{"0.10.0", true, false},
+		{"0.11.0", true, false},
+		{"0.12.0", true, true},
+		{"0.13.0", true, false},
+		{"0.14.0", false, false},
+		{"0.15.0", true, false},
+	} {
+		v, err := ParseVersion(item.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		app.Items = append(app.Items, Item{Version: *v, Downloaded: item.downloaded, Current: item.current})
+	}
+	app.Items = append(app.Items, Item{Custom: true, CustomName: "local", Downloaded: true})
+
+	registry := &ProjectRegistry{Projects: []string{exact, partial, gone}}
+	names := []string{}
+	for _, item := range app.gcCandidates(registry) {
+		names = append(names, item.Name())
+	}
+
+	if want := []string{"0.10.0", "0.15.0"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("gc candidates = %v, want %v", names, want)
+	}
+
+	// The registry is only pruned with the lock held exclusively.
+	if len(registry.Projects) != 3 {
+		t.Errorf("registry was pruned without the lock: %v", registry.Projects)
+	}
+}
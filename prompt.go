@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+)
+
+// Reports whether --yes or ZIG_TOOLCHAIN_NONINTERACTIVE=1 was given, in which
+// case no prompts are shown and every question is answered with yes.
+func nonInteractiveRequested(args *Args) bool {
+	return args.Has("yes") || os.Getenv("ZIG_TOOLCHAIN_NONINTERACTIVE") == "1"
+}
+
+// Asks the user a yes/no question, defaulting to yes. Always returns true
+// when prompts are disabled with --yes or stdin is not a terminal.
+func (app *AppState) confirm(question string) bool {
+	if app.AssumeYes || !isatty.IsTerminal(os.Stdin.Fd()) {
+		return true
+	}
+
+	return ask(question, "[Y/n]", true)
+}
+
+// Asks the user a yes/no question before deleting something, defaulting to
+// no. Unlike confirm, it returns false when stdin is not a terminal, so that
+// scripts have to opt in with --yes.
+func (app *AppState) confirmDestructive(question string) bool {
+	if app.AssumeYes {
+		return true
+	}
+
+	if !isatty.IsTerminal(os.Stdin.Fd()) {
+		logger.warnf("%s Not asking since stdin is not a terminal, pass --yes to confirm.\n", question)
+		return false
+	}
+
+	return ask(question, "[y/N]", false)
+}
+
+// Asks for confirmation before removing items, listing them with the space
+// they take up. Exits when the user declines.
+func (app *AppState) confirmRemoval(items []*Item) {
+	if len(items) == 0 {
+		return
+	}
+
+	total := int64(0)
+	for _, item := range items {
+		size := itemDiskUsage(item)
+		total += size
+		if len(items) > 1 {
+			logger.infof("  %s (%s)\n", item.Version.String(), humanSize(size))
+		}
+	}
+
+	question := fmt.Sprintf("Remove %d version(s), freeing %s?", len(items), humanSize(total))
+	if len(items) == 1 {
+		question = fmt.Sprintf("Remove zig %s, freeing %s?", items[0].Version.String(), humanSize(total))
+	}
+	if !app.confirmDestructive(question) {
+		logger.errorf("Aborted.\n")
+		os.Exit(1)
+	}
+}
+
+func ask(question string, choices string, fallback bool) bool {
+	fmt.Printf("%s %s ", question, choices)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "":
+		return fallback
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// Returns the space the tarball and the extracted toolchain of item take up.
+func itemDiskUsage(item *Item) int64 {
+	size := fileSize(item.LocalPath)
+	if dir := extractedDirForItem(item); isExtracted(dir) {
+		size += dirSize(dir)
+	}
+
+	return size
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Retention policy of the prune command.
+type PruneConfig struct {
+	// Number of most recent dev builds to keep.
+	KeepDev int `json:"keep_dev"`
+	// Keep versions used within this many days.
+	KeepUsedWithinDays int `json:"keep_used_within_days"`
+	// Also prune stable releases, which are kept otherwise.
+	PruneStable bool `json:"prune_stable"`
+}
+
+func NewPruneConfig() PruneConfig {
+	return PruneConfig{KeepDev: 3, KeepUsedWithinDays: 30}
+}
+
+// Returns why item is kept under the retention policy, or an empty string if
+// it should be removed. devRank is the number of newer downloaded dev builds.
+func (app *AppState) pruneKeepReason(item *Item, devRank int, history *History, registry *ProjectRegistry) string {
+	policy := app.Config.Prune
+
+	if item.Current {
+		return "active"
+	}
+
+	if projects := app.projectsReferencing(registry, item); len(projects) > 0 {
+		return fmt.Sprintf("pinned by %s", strings.Join(projects, ", "))
+	}
+
+	if !item.Version.Dev && !policy.PruneStable {
+		return "stable release"
+	}
+
+	if item.Version.Dev && devRank < policy.KeepDev {
+		return fmt.Sprintf("one of the %d most recent dev builds", policy.KeepDev)
+	}
+
+	if policy.KeepUsedWithinDays > 0 {
+		lastUsed := history.lastUsed(item)
+		if time.Since(lastUsed) < time.Duration(policy.KeepUsedWithinDays)*24*time.Hour {
+			return fmt.Sprintf("used on %s", lastUsed.Format("2006-01-02"))
+		}
+	}
+
+	return ""
+}
+
+// Removes the downloaded versions not kept by the retention policy.
+func (app *AppState) commandPrune() {
+	history, err := LoadHistory()
+	if err != nil {
+		app.fail(err)
+	}
+
+	registry, err := LoadProjectRegistry()
+	if err != nil {
+		app.fail(err)
+	}
+
+	// Items are sorted newest first.
+	devRank := 0
+	candidates := []*Item{}
+	for i := 0; i < len(app.Items); i++ {
+		item := &app.Items[i]
+		if !item.Downloaded || item.Custom {
+			continue
+		}
+
+		reason := app.pruneKeepReason(item, devRank, history, registry)
+		if item.Version.Dev {
+			devRank++
+		}
+
+		if reason != "" {
+			logger.infof("Keeping %s (%s)\n", item.Version.String(), reason)
+			continue
+		}
+
+		candidates = append(candidates, item)
+	}
+
+	app.removeItems(candidates)
+}
@@ -0,0 +1,30 @@
+//go:build darwin
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+const quarantineAttr = "com.apple.quarantine"
+
+// Removes the quarantine attribute from every file under dir, so Gatekeeper
+// doesn't prompt for (or kill) the extracted binaries.
+func stripQuarantine(dir string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		err = unix.Lremovexattr(p, quarantineAttr)
+		if err != nil && !errors.Is(err, unix.ENOATTR) {
+			return err
+		}
+
+		return nil
+	})
+}
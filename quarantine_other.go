@@ -0,0 +1,8 @@
+//go:build !darwin
+
+package main
+
+// Quarantine attributes only exist on macOS.
+func stripQuarantine(dir string) error {
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/fatih/color"
+)
+
+type queueResult struct {
+	name string
+	err  error
+}
+
+// Downloads several versions, sequentially or in parallel, and reports the
+// result for each one at the end instead of stopping at the first failure.
+func (app *AppState) commandDownloadQueue(versions []string, parallel bool) {
+	results := make([]queueResult, len(versions))
+	items := make([]*Item, len(versions))
+	total := int64(0)
+	count := 0
+
+	// Names resolving to the same tarball, e.g. stable and its version, are
+	// only downloaded once, and share the result of the first one.
+	sameAs := map[int]int{}
+
+	for i, v := range versions {
+		results[i].name = v
+		item, ok := app.resolveName(v)
+		if !ok {
+			results[i].err = &VersionNotFoundError{Name: v}
+			continue
+		}
+
+		first := -1
+		for j := 0; j < i; j++ {
+			if items[j] != nil && items[j].LocalPath == item.LocalPath {
+				first = j
+				break
+			}
+		}
+		if first >= 0 {
+			sameAs[i] = first
+			continue
+		}
+
+		items[i] = item
+		count++
+		if !item.Downloaded {
+			total += item.Size
+		}
+	}
+
+	if !app.DryRun && !app.confirm(fmt.Sprintf("Download %d version(s) (%s)?", count, humanSize(total))) {
+		logger.errorf("Aborted.\n")
+		os.Exit(1)
+	}
+
+	app.ParallelDownloads = parallel
+	// Build the shared client before the downloads use it concurrently.
+	app.httpClient()
+	var wg sync.WaitGroup
+	for i, item := range items {
+		if item == nil {
+			continue
+		}
+
+		if !parallel {
+			results[i].err = app.downloadItem(item)
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, item *Item) {
+			defer app.recoverPanic()
+			defer wg.Done()
+			results[i].err = app.downloadItem(item)
+		}(i, item)
+	}
+	wg.Wait()
+	for i, first := range sameAs {
+		results[i].err = results[first].err
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	failed := 0
+	logger.infof("\n")
+	for _, result := range results {
+		if result.err != nil {
+			failed++
+			logger.errorf("%s %s: %s\n", red("==>"), result.name, result.err)
+		} else {
+			logger.infof("%s %s\n", green("==>"), result.name)
+		}
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
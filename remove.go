@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Deletes the tarball and the extracted toolchain of item. The active
+// version is only removed with --force, which also deactivates it.
+func (app *AppState) removeItem(item *Item) error {
+	if err := app.removalError(item); err != nil {
+		return err
+	}
+
+	if item.Current {
+		if err := unlinkToolchain(); err != nil {
+			return err
+		}
+		os.Remove(activeDirPath())
+		os.Remove(currentVersionPath())
+		item.Current = false
+	}
+
+	if err := os.RemoveAll(extractedDirForItem(item)); err != nil {
+		return err
+	}
+
+	if err := os.Remove(item.LocalPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	item.Downloaded = false
+	app.recordRemoved(item, true)
+
+	return nil
+}
+
+// Returns why item can't be removed, if it can't.
+func (app *AppState) removalError(item *Item) error {
+	if item.Current && !app.Force {
+		return fmt.Errorf("zig %s is the active version, pass --force to remove it anyway", item.Version.String())
+	}
+
+	return nil
+}
+
+func (app *AppState) commandRemove(pin string) {
+	item, ok := app.resolveName(pin)
+	if !ok {
+		app.fail(&VersionNotFoundError{Name: pin})
+	}
+	if !item.Downloaded {
+		logger.errorf("Version is not downloaded!\n")
+		os.Exit(1)
+	}
+
+	if item.Custom {
+		logger.errorf("%s is a custom toolchain, use `zig-toolchain link --remove %s` instead.\n", item.Name(), item.Name())
+		os.Exit(1)
+	}
+
+	if app.DryRun {
+		if err := app.removalError(item); err != nil {
+			app.fail(err)
+		}
+		app.printRemovalPlan(item)
+		return
+	}
+
+	if err := app.removalError(item); err != nil {
+		app.fail(err)
+	}
+	if item.Current && !app.confirmDestructive(fmt.Sprintf("zig %s is the active version. Remove it anyway?", item.Version.String())) {
+		logger.errorf("Aborted.\n")
+		os.Exit(1)
+	}
+
+	logger.infof("Removing %s...", item.Version.String())
+	if err := app.removeItem(item); err != nil {
+		app.fail(err)
+	}
+	logger.infof("Done!\n")
+
+	if err := pruneStore(); err != nil {
+		app.fail(err)
+	}
+}
+
+// Removes every downloaded dev build except the active one.
+func (app *AppState) commandRemoveAllDev() {
+	candidates := []*Item{}
+	for i := 0; i < len(app.Items); i++ {
+		item := &app.Items[i]
+		if !item.Downloaded || item.Custom || !item.Version.Dev {
+			continue
+		}
+
+		if item.Current && !app.Force {
+			logger.infof("Keeping %s (active)\n", item.Version.String())
+			continue
+		}
+
+		candidates = append(candidates, item)
+	}
+
+	app.removeItems(candidates)
+}
+
+// Removes items after asking for confirmation, or only prints what would be
+// removed with --dry-run.
+func (app *AppState) removeItems(items []*Item) {
+	if app.DryRun {
+		freed := int64(0)
+		for _, item := range items {
+			freed += app.printRemovalPlan(item)
+		}
+		fmt.Printf("Would remove %d version(s), freeing %s.\n", len(items), humanSize(freed))
+		return
+	}
+
+	app.confirmRemoval(items)
+
+	for _, item := range items {
+		logger.infof("Removing %s...", item.Version.String())
+		if err := app.removeItem(item); err != nil {
+			app.fail(err)
+		}
+		logger.infof("Done!\n")
+	}
+
+	if err := pruneStore(); err != nil {
+		app.fail(err)
+	}
+
+	logger.infof("Removed %d version(s).\n", len(items))
+}
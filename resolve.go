@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Parses a partial version, i.e. just a major or a major and minor version
+// like `0` or `0.12`, into its numbers.
+func parsePartialVersion(s string) ([]int, bool) {
+	parts := strings.Split(s, ".")
+	if len(parts) > 2 {
+		return nil, false
+	}
+
+	numbers := []int{}
+	for _, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		numbers = append(numbers, n)
+	}
+
+	return numbers, true
+}
+
+// Returns the newest release matching the partial version s, e.g. the newest
+// 0.12.x release for `0.12`. Prereleases are never picked.
+func (app *AppState) itemForPartialVersion(s string) (*Item, bool) {
+	numbers, ok := parsePartialVersion(s)
+	if !ok {
+		return nil, false
+	}
+
+	var newest *Item
+	for i := 0; i < len(app.Items); i++ {
+		item := &app.Items[i]
+		if item.Custom || item.Version.isPrerelease() || !(item.Indexed || item.Downloaded) {
+			continue
+		}
+		if item.Version.Major != numbers[0] || (len(numbers) > 1 && item.Version.Minor != numbers[1]) {
+			continue
+		}
+		if newest == nil || item.Version.moreThan(newest.Version) {
+			newest = item
+		}
+	}
+
+	return newest, newest != nil
+}
+
+// A single comparison against a version, e.g. `>=0.12.0`.
+type versionComparator struct {
+	op      string
+	version Version
+}
+
+func (c versionComparator) matches(v Version) bool {
+	switch cmp := v.compare(c.version); c.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	default:
+		return cmp == 0
+	}
+}
+
+// A version range: caret (`^0.12.0`) and tilde (`~0.12.1`) ranges, or
+// comparisons (`>=0.11.0 <0.13.0`), all of which have to match.
+type VersionRange struct {
+	comparators []versionComparator
+
+	// Prereleases only match ranges that mention one.
+	prerelease bool
+}
+
+// Parses a version operand of a range, which may leave out the minor and
+// patch versions. Returns how many of the numbers were given.
+func parseRangeVersion(s string) (Version, int, error) {
+	if numbers, ok := parsePartialVersion(s); ok {
+		v := Version{Major: numbers[0]}
+		if len(numbers) > 1 {
+			v.Minor = numbers[1]
+		}
+		return v, len(numbers), nil
+	}
+
+	v, err := ParseVersion(s)
+	if err != nil {
+		return Version{}, 0, err
+	}
+	return *v, 3, nil
+}
+
+// Parses a version range. Plain versions aren't ranges, they are looked up
+// exactly.
+func ParseVersionRange(s string) (*VersionRange, error) {
+	result := &VersionRange{}
+
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' })
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("Failed to parse version range: %s", s)
+	}
+
+	for _, f := range fields {
+		op := ""
+		for _, o := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+			if strings.HasPrefix(f, o) {
+				op = o
+				break
+			}
+		}
+		if op == "" {
+			return nil, fmt.Errorf("Failed to parse version range: %s", s)
+		}
+
+		v, given, err := parseRangeVersion(strings.TrimPrefix(f, op))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse version range: %s", s)
+		}
+		result.prerelease = result.prerelease || v.isPrerelease()
+
+		switch op {
+		case "^":
+			// Compatible versions: the first non-zero number can't change.
+			upper := Version{Major: 0, Minor: 0, Patch: v.Patch + 1}
+			if v.Major > 0 || given == 1 {
+				upper = Version{Major: v.Major + 1}
+			} else if v.Minor > 0 || given == 2 {
+				upper = Version{Major: 0, Minor: v.Minor + 1}
+			}
+			result.comparators = append(result.comparators, versionComparator{">=", v}, versionComparator{"<", lowestPrerelease(upper)})
+		case "~":
+			// Patch updates only, or minor ones when only a major is given.
+			upper := Version{Major: v.Major, Minor: v.Minor + 1}
+			if given == 1 {
+				upper = Version{Major: v.Major + 1}
+			}
+			result.comparators = append(result.comparators, versionComparator{">=", v}, versionComparator{"<", lowestPrerelease(upper)})
+		default:
+			result.comparators = append(result.comparators, versionComparator{op, v})
+		}
+	}
+
+	return result, nil
+}
+
+// Returns the lowest possible prerelease of v, so that an upper bound of
+// 0.13.0 excludes the 0.13.0 dev builds too.
+func lowestPrerelease(v Version) Version {
+	v.Prerelease = "0"
+	return v
+}
+
+func (r *VersionRange) matches(v Version) bool {
+	if v.isPrerelease() && !r.prerelease {
+		return false
+	}
+
+	for _, c := range r.comparators {
+		if !c.matches(v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Returns the newest version in the index or downloaded that matches the
+// range s.
+func (app *AppState) itemForRange(s string) (*Item, bool) {
+	r, err := ParseVersionRange(s)
+	if err != nil {
+		return nil, false
+	}
+
+	var newest *Item
+	for i := 0; i < len(app.Items); i++ {
+		item := &app.Items[i]
+		if item.Custom || !(item.Indexed || item.Downloaded) || !r.matches(item.Version) {
+			continue
+		}
+		if newest == nil || item.Version.moreThan(newest.Version) {
+			newest = item
+		}
+	}
+
+	return newest, newest != nil
+}
+
+// Reports whether name is a partial version or a range, which resolve to a
+// different version as new ones are released.
+func isFuzzyVersion(name string) bool {
+	if _, partial := parsePartialVersion(name); partial {
+		return true
+	}
+
+	_, err := ParseVersionRange(name)
+	return err == nil
+}
+
+// Same as itemForPin, but tells the user what a partial version, a range or
+// a Mach nomination resolved to.
+func (app *AppState) resolveName(name string) (*Item, bool) {
+	item, ok := app.itemForPin(name)
+	if ok && !item.Custom && !app.structuredOutput() && (isFuzzyVersion(name) || isNomination(name)) {
+		logger.infof("resolved %s -> %s\n", name, item.Name())
+	}
+
+	return item, ok
+}
+
+// Prints the version that a version, a partial version, a range or an alias
+// resolves to.
+func (app *AppState) commandResolve(name string) {
+	item, ok := app.itemForPin(name)
+	if !ok {
+		app.fail(&VersionNotFoundError{Name: name})
+	}
+
+	fmt.Printf("%s\n", item.Name())
+}
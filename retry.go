@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultRetries    = 3
+	DefaultRetryDelay = 1000
+)
+
+type HttpStatusError struct {
+	Url        string
+	StatusCode int
+}
+
+func (e *HttpStatusError) Error() string {
+	return fmt.Sprintf("GET %s: %d %s", e.Url, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+func checkResponseStatus(res *http.Response) error {
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return &HttpStatusError{Url: res.Request.URL.String(), StatusCode: res.StatusCode}
+	}
+
+	return nil
+}
+
+// Reports whether an operation that failed with err is worth retrying.
+// Checksum mismatches and client errors (e.g. 404) won't go away by
+// themselves.
+func isRetryable(err error) bool {
+	var checksumErr *ChecksumError
+	if errors.As(err, &checksumErr) {
+		return false
+	}
+
+	var statusErr *HttpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	return true
+}
+
+// Calls fn until it succeeds, it fails with a non retryable error or the
+// configured number of attempts is exhausted, doubling the delay between
+// attempts each time.
+func (app *AppState) withRetry(what string, fn func() error) error {
+	attempts := app.Config.Retries
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := time.Duration(app.Config.RetryDelay) * time.Millisecond
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		if app.context().Err() != nil {
+			return err
+		}
+
+		if !isRetryable(err) || attempt >= attempts {
+			if attempt > 1 {
+				return fmt.Errorf("%s failed after %d attempts: %w", what, attempt, err)
+			}
+			return fmt.Errorf("%s failed: %w", what, err)
+		}
+
+		logger.warnf("\n%s failed (%s), retrying in %s...\n", what, err, delay)
+		select {
+		case <-app.context().Done():
+			return app.context().Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
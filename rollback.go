@@ -0,0 +1,38 @@
+package main
+
+import "os"
+
+// Reactivates the version that was active before the last activation.
+func (app *AppState) commandRollback() {
+	history, err := LoadHistory()
+	if err != nil {
+		app.fail(err)
+	}
+
+	current := ""
+	if item, ok := app.GetCurrentActiveItem(); ok {
+		current = item.Name()
+		if !item.Custom {
+			current = item.Version.FullString()
+		}
+	}
+
+	for i := len(history.Activations) - 1; i >= 0; i-- {
+		name := history.Activations[i]
+		if name == current {
+			continue
+		}
+
+		item, ok := app.itemForPin(name)
+		if !ok {
+			app.fail(&VersionNotFoundError{Name: name})
+		}
+
+		logger.infof("Rolling back to zig %s\n", name)
+		app.commandActivateItem(item)
+		return
+	}
+
+	logger.errorf("No previous version to roll back to!\n")
+	os.Exit(1)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// Prints the indexed and local versions whose version, release date or
+// commit contains query, e.g. `0.12` or `dev.24`.
+func (app *AppState) commandSearch(query string) {
+	query = strings.ToLower(query)
+
+	items := []*Item{}
+	for i := 0; i < len(app.Items); i++ {
+		item := &app.Items[i]
+		if (item.Indexed || item.Downloaded || item.Custom) && item.matchesQuery(query) {
+			items = append(items, item)
+		}
+	}
+
+	if app.structuredOutput() {
+		app.printItems(items)
+		return
+	}
+	if len(items) == 0 {
+		logger.errorf("No versions match %s!\n", query)
+		os.Exit(1)
+	}
+
+	app.printItemTable(items, false)
+}
+
+func (item *Item) matchesQuery(query string) bool {
+	for _, field := range []string{item.Name(), item.Version.FullString(), item.Date, item.Version.Commit} {
+		if field != "" && strings.Contains(strings.ToLower(field), query) {
+			return true
+		}
+	}
+
+	return false
+}
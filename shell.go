@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// Environment variable holding the version of the session started by the
+// shell command, e.g. for shell prompts.
+const shellVersionEnv = "ZIG_TOOLCHAIN_SHELL"
+
+// Returns the user's shell, to start sessions with.
+func userShell() string {
+	if getHostOs() == "windows" {
+		if shell := os.Getenv("COMSPEC"); shell != "" {
+			return shell
+		}
+		return "cmd.exe"
+	}
+
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+
+	return "/bin/sh"
+}
+
+// Starts a shell with version first in PATH, independent of the active
+// version, and exits with its exit code once it's done. With printOnly the
+// environment is printed as commands to eval instead.
+func (app *AppState) commandShell(version string, printOnly bool) {
+	item, ok := app.itemForPin(version)
+	if !ok {
+		app.fail(&VersionNotFoundError{Name: version})
+	}
+
+	dir, err := app.ensureInstalled(item)
+	if err != nil {
+		app.fail(err)
+	}
+	recordUsage(item)
+
+	vars := append(toolchainVars(item, dir), EnvVar{Name: shellVersionEnv, Value: item.Name()})
+	if printOnly {
+		fmt.Print(formatEnv(detectShell(), vars))
+		return
+	}
+
+	shell := userShell()
+
+	if os.Getenv(shellVersionEnv) != "" {
+		logger.warnf("Already in a zig-toolchain shell (zig %s), starting a nested one.\n", os.Getenv(shellVersionEnv))
+	}
+	logger.infof("Starting %s with zig %s, exit it to go back.\n", shell, item.Name())
+	app.releaseLock()
+
+	cmd := exec.Command(shell)
+	cmd.Env = environWith(vars)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	} else if err != nil {
+		app.fail(err)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import "fmt"
+
+const (
+	// Rough ratio between the extracted toolchain and its .tar.xz tarball.
+	ExtractedSizeRatio = 7
+)
+
+func humanSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// Describes the download size of an item and its estimated extracted size.
+func (item *Item) sizeDescription() string {
+	if item.Size <= 0 {
+		return "unknown size"
+	}
+
+	return fmt.Sprintf("%s, ~%s extracted", humanSize(item.Size), humanSize(item.Size*ExtractedSizeRatio))
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+)
+
+const smokeTestSource = `const std = @import("std");
+
+pub fn main() void {
+    std.debug.print("Hello, world!\n", .{});
+}
+`
+
+// Builds and runs a hello-world program with the given item's toolchain. If
+// the item isn't extracted yet its tarball is extracted to a temporary
+// directory first.
+func (app *AppState) commandSmokeTest(item *Item) {
+	if !item.Downloaded && !item.Custom {
+		logger.errorf("Version is not downloaded!\n")
+		os.Exit(1)
+	}
+
+	tmp, err := os.MkdirTemp("", "zig-toolchain-smoke-test")
+	if err != nil {
+		app.fail(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	zig := path.Join(extractedDirForItem(item), zigExeName())
+	if item.Custom {
+		zig = path.Join(item.LocalPath, zigExeName())
+	} else if !isExtracted(extractedDirForItem(item)) {
+		logger.infof("Extracting...")
+		toolchainDir := path.Join(tmp, "toolchain")
+		if err = os.Mkdir(toolchainDir, os.ModePerm); err != nil {
+			app.fail(err)
+		}
+		if err = extractTarball(item.LocalPath, toolchainDir); err != nil {
+			app.fail(err)
+		}
+		if err = fixToolchainPermissions(path.Join(toolchainDir, tarballBaseName(item.LocalPath))); err != nil {
+			app.fail(err)
+		}
+		zig = path.Join(toolchainDir, tarballBaseName(item.LocalPath), zigExeName())
+		logger.infof("Done!\n")
+	}
+
+	projectDir := path.Join(tmp, "project")
+	if err = os.Mkdir(projectDir, os.ModePerm); err != nil {
+		app.fail(err)
+	}
+	if err = os.WriteFile(path.Join(projectDir, "main.zig"), []byte(smokeTestSource), 0644); err != nil {
+		app.fail(err)
+	}
+
+	logger.infof("Building hello world with zig %s...", item.Name())
+	start := time.Now()
+	cmd := exec.Command(zig, "build-exe", "main.zig")
+	cmd.Dir = projectDir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		logger.errorf("Failed!\n\n%s\n", string(out))
+		os.Exit(1)
+	}
+	buildTime := time.Since(start)
+	logger.infof("Done! (%s)\n", buildTime.Round(time.Millisecond))
+
+	exe := "main"
+	if getHostOs() == "windows" {
+		exe += ".exe"
+	}
+
+	logger.infof("Running...")
+	start = time.Now()
+	cmd = exec.Command(path.Join(projectDir, exe))
+	out, err = cmd.CombinedOutput()
+	if err != nil || !strings.Contains(string(out), "Hello, world!") {
+		logger.errorf("Failed!\n\n%s\n", string(out))
+		os.Exit(1)
+	}
+	logger.infof("Done! (%s)\n", time.Since(start).Round(time.Millisecond))
+
+	logger.infof("\nzig %s is working.\n", item.Name())
+}
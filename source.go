@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+)
+
+const (
+	ArchiveSource    = "src"
+	ArchiveBootstrap = "bootstrap"
+)
+
+// Returns an item for another archive of item, described by entry and
+// stored in dir.
+func (app *AppState) archiveItem(item *Item, entry *ZigIndexFileEntry, dir string) *Item {
+	result := &Item{
+		Version:     item.Version,
+		Indexed:     true,
+		RemoteUrl:   app.Config.rewriteUrl(entry.Tarball),
+		OriginalUrl: entry.Tarball,
+		LocalPath:   path.Join(dir, path.Base(entry.Tarball)),
+		Shasum:      entry.Shasum,
+	}
+	result.Size, _ = strconv.ParseInt(entry.Size, 10, 64)
+
+	if _, err := os.Stat(result.LocalPath); err == nil {
+		result.Downloaded = true
+	}
+
+	return result
+}
+
+// Returns an item describing the source or bootstrap archive of item, stored
+// under src/ instead of tarballs/.
+func (app *AppState) sourceItem(item *Item, kind string) (*Item, bool) {
+	entry := item.Src
+	if kind == ArchiveBootstrap {
+		entry = item.Bootstrap
+	}
+	if entry == nil {
+		return nil, false
+	}
+
+	return app.archiveItem(item, entry, localDirPath("src")), true
+}
+
+func (app *AppState) commandDownloadSource(pin string, kind string) {
+	item, ok := app.itemForPin(pin)
+	if !ok {
+		app.fail(&VersionNotFoundError{Name: pin})
+	}
+
+	source, ok := app.sourceItem(item, kind)
+	if !ok {
+		logger.errorf("No %s archive for zig %s in the index!\n", kind, item.Name())
+		os.Exit(1)
+	}
+
+	if source.Downloaded {
+		logger.infof("Archive already downloaded: %s\n", source.LocalPath)
+		return
+	}
+
+	app.downloadArchive(source, fmt.Sprintf("the %s archive of zig %s", kind, item.Version.String()))
+}
+
+func (app *AppState) commandDownloadTarget(pin string, target string) {
+	item, ok := app.itemForPin(pin)
+	if !ok {
+		app.fail(&VersionNotFoundError{Name: pin})
+	}
+
+	entry, ok := item.Files[target]
+	if !ok {
+		logger.errorf("No %s tarball for zig %s in the index!\n", target, item.Name())
+		os.Exit(1)
+	}
+
+	archive := app.archiveItem(item, entry, localDirPath("tarballs", target))
+	if archive.Downloaded {
+		logger.infof("Tarball already downloaded: %s\n", archive.LocalPath)
+		return
+	}
+
+	if err := os.MkdirAll(path.Dir(archive.LocalPath), os.ModePerm); err != nil {
+		app.fail(err)
+	}
+
+	app.downloadArchive(archive, fmt.Sprintf("zig %s for %s", item.Version.String(), target))
+}
+
+// Downloads an archive item after confirmation, description saying what
+// it is.
+func (app *AppState) downloadArchive(archive *Item, description string) {
+	if !app.DryRun && !app.confirm(fmt.Sprintf("Download %s (%s)?", description, humanSize(archive.Size))) {
+		logger.errorf("Aborted.\n")
+		os.Exit(1)
+	}
+
+	if err := app.downloadItem(archive); err != nil {
+		app.fail(err)
+	}
+
+	if !app.DryRun {
+		logger.infof("Saved to %s\n", archive.LocalPath)
+	}
+}
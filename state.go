@@ -0,0 +1,307 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// Version of the state file format, bumped on incompatible changes.
+	StateVersion = 1
+)
+
+// What zig-toolchain knows about the local versions, so that it doesn't have
+// to be derived from file names on every run.
+type State struct {
+	Version int `json:"version"`
+	// Keyed by the name of the version's directory in versions/, which is
+	// also its tarball's base name.
+	Versions map[string]*VersionState `json:"versions"`
+	// Name of the active version, if it isn't a custom toolchain.
+	Active string `json:"active,omitempty"`
+
+	// Whether it was migrated or pruned since it was read, and differs from
+	// the file.
+	dirty bool
+}
+
+type VersionState struct {
+	Version string `json:"version"`
+	// Path of the tarball, which is kept even after it was deleted, since
+	// it also names the extracted directory.
+	Tarball     string     `json:"tarball"`
+	HasTarball  bool       `json:"has_tarball"`
+	Dir         string     `json:"dir,omitempty"`
+	Shasum      string     `json:"shasum,omitempty"`
+	Size        int64      `json:"size,omitempty"`
+	Downloaded  *time.Time `json:"downloaded,omitempty"`
+	Extracted   *time.Time `json:"extracted,omitempty"`
+	ActivatedAt *time.Time `json:"activated_at,omitempty"`
+}
+
+func statePath() string {
+	return localDirPath("state.json")
+}
+
+// Loads the state file. Without one, the state is migrated from what is on
+// disk, the way older releases found the local versions, and only saved by
+// whoever holds the lock exclusively.
+func LoadState() (*State, error) {
+	data, err := os.ReadFile(statePath())
+	if errors.Is(err, fs.ErrNotExist) {
+		state := migrateState()
+		state.dirty = true
+		return state, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	state := &State{}
+	if err = json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("%s: %w", statePath(), err)
+	}
+	if state.Version > StateVersion {
+		return nil, fmt.Errorf("%s was written by a newer release of zig-toolchain", statePath())
+	}
+	state.Version = StateVersion
+	if state.Versions == nil {
+		state.Versions = map[string]*VersionState{}
+	}
+
+	return state, nil
+}
+
+func (s *State) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := statePath() + ".tmp"
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, statePath())
+}
+
+// Builds the state from the tarballs, the extracted versions and the active
+// version marker.
+func migrateState() *State {
+	state := &State{Version: StateVersion, Versions: map[string]*VersionState{}}
+
+	if entries, err := os.ReadDir(localDirPath("tarballs")); err == nil {
+		for _, entry := range entries {
+			if archiveExt(entry.Name()) == "" {
+				continue
+			}
+
+			name := tarballBaseName(entry.Name())
+			version, err := tarballVersion(localDirPath("tarballs", entry.Name()))
+			if err != nil {
+				continue
+			}
+
+			v := state.entry(name, localDirPath("tarballs", entry.Name()))
+			v.Version = version.FullString()
+			v.HasTarball = true
+			if info, err := entry.Info(); err == nil {
+				v.Size = info.Size()
+				v.Downloaded = utcTime(info.ModTime())
+			}
+		}
+	}
+
+	if entries, err := os.ReadDir(localDirPath("versions")); err == nil {
+		for _, entry := range entries {
+			name := entry.Name()
+			dir := localDirPath("versions", name)
+			if strings.HasPrefix(name, ".") || !isExtracted(dir) {
+				continue
+			}
+
+			version, err := extractedVersion(name)
+			if err != nil {
+				continue
+			}
+
+			v := state.entry(name, localDirPath("tarballs", name+tarballExt()))
+			v.Version = version.FullString()
+			v.Dir = dir
+			if manifest, err := readManifest(dir); err == nil {
+				v.Shasum = manifest.Shasum
+				v.Extracted = utcTime(manifest.ExtractedAt)
+			}
+		}
+	}
+
+	if name, ok := currentVersionName(); ok {
+		state.Active = name
+		// Versions extracted into current/ by older releases.
+		if _, ok := state.Versions[name]; !ok {
+			if version, err := extractedVersion(name); err == nil {
+				v := state.entry(name, localDirPath("tarballs", name+tarballExt()))
+				v.Version = version.FullString()
+				v.Dir = localDirPath("current", name)
+			}
+		}
+	}
+
+	return state
+}
+
+// Reports whether the archive at p is a tarball for the host, which are kept
+// in tarballs/ itself, unlike those of download --src or --target.
+func isHostTarball(p string) bool {
+	return path.Dir(filepath.ToSlash(p)) == localDirPath("tarballs")
+}
+
+func utcTime(t time.Time) *time.Time {
+	t = t.UTC()
+	return &t
+}
+
+// Returns the entry for the version named name, adding it with the given
+// tarball path if needed.
+func (s *State) entry(name string, tarball string) *VersionState {
+	v, ok := s.Versions[name]
+	if !ok {
+		v = &VersionState{Tarball: tarball}
+		s.Versions[name] = v
+	}
+
+	return v
+}
+
+// Loads the state, applies update to it and saves it. Like the history,
+// failing to do so never fails the command using it: the state is checked
+// against the disk on every run anyway.
+func (app *AppState) updateState(update func(*State)) {
+	app.lockExclusively()
+	app.stateMutex.Lock()
+	defer app.stateMutex.Unlock()
+
+	state, err := LoadState()
+	if err != nil {
+		return
+	}
+
+	update(state)
+	state.Save()
+}
+
+// Records that the tarball of item was downloaded.
+func (app *AppState) recordDownloaded(item *Item) {
+	// Source archives and tarballs for other platforms aren't versions that
+	// can be activated.
+	if !isHostTarball(item.LocalPath) {
+		return
+	}
+
+	app.updateState(func(s *State) {
+		v := s.entry(tarballBaseName(item.LocalPath), item.LocalPath)
+		v.Version = item.Version.FullString()
+		v.Tarball = item.LocalPath
+		v.HasTarball = true
+		v.Downloaded = utcTime(time.Now())
+		if item.Shasum != "" {
+			v.Shasum = item.Shasum
+		}
+		if info, err := os.Stat(item.LocalPath); err == nil {
+			v.Size = info.Size()
+		}
+	})
+}
+
+// Records that item was extracted into its directory in versions/.
+func (app *AppState) recordExtracted(item *Item) {
+	app.updateState(func(s *State) {
+		v := s.entry(tarballBaseName(item.LocalPath), item.LocalPath)
+		v.Version = item.Version.FullString()
+		v.Dir = extractedDirForItem(item)
+		v.Extracted = utcTime(time.Now())
+		if item.Shasum != "" {
+			v.Shasum = item.Shasum
+		}
+	})
+}
+
+// Records that the tarball of item was deleted, and with removeDir that the
+// whole version was removed.
+func (app *AppState) recordRemoved(item *Item, removeDir bool) {
+	app.updateState(func(s *State) {
+		name := tarballBaseName(item.LocalPath)
+		if removeDir {
+			delete(s.Versions, name)
+			if s.Active == name {
+				s.Active = ""
+			}
+		} else if v, ok := s.Versions[name]; ok {
+			v.HasTarball = false
+		}
+	})
+}
+
+// Records which version is active, where an empty name means none is or a
+// custom toolchain is.
+func (app *AppState) recordActive(name string) {
+	app.updateState(func(s *State) {
+		s.Active = name
+		if v, ok := s.Versions[name]; ok {
+			v.ActivatedAt = utcTime(time.Now())
+		}
+	})
+}
+
+// Loads the state, dropping the versions whose files are gone. Both the
+// tarball and the directory are checked, as they may have been deleted by
+// hand. Commands sharing the lock only do so in memory, so that they never
+// write the file at the same time.
+func (app *AppState) loadLocalState() *State {
+	state, err := LoadState()
+	if err != nil {
+		app.fail(err)
+	}
+
+	for name, v := range state.Versions {
+		// Recorded by releases that didn't tell them apart.
+		if !isHostTarball(v.Tarball) {
+			delete(state.Versions, name)
+			state.dirty = true
+			continue
+		}
+		if v.HasTarball {
+			if _, err := os.Stat(v.Tarball); err != nil {
+				v.HasTarball = false
+				state.dirty = true
+			}
+		}
+		if v.Dir != "" && !isExtracted(v.Dir) {
+			v.Dir = ""
+			state.dirty = true
+		}
+		if !v.HasTarball && v.Dir == "" {
+			delete(state.Versions, name)
+			state.dirty = true
+		}
+	}
+	if _, ok := state.Versions[state.Active]; state.Active != "" && !ok {
+		state.Active = ""
+		state.dirty = true
+	}
+
+	if state.dirty && app.holdsExclusiveLock() {
+		if err = state.Save(); err == nil {
+			state.dirty = false
+		}
+	}
+
+	return state
+}
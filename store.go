@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Content-addressed store of the files of extracted toolchains. With dedupe
+// enabled, identical files across versions/ are hardlinks to the same file
+// in the store, which saves a lot of space when keeping many dev builds.
+func storePath(p ...string) string {
+	return localDirPath(append([]string{"store"}, p...)...)
+}
+
+// Replaces every regular file under dir with a hardlink to its copy in the
+// store, adding the files the store doesn't have yet. Returns the number of
+// bytes saved.
+func dedupeDir(dir string) (int64, error) {
+	saved := int64(0)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		sum, err := fileSha256(p)
+		if err != nil {
+			return err
+		}
+
+		// Hardlinks share their permissions, so files only match if those
+		// do too.
+		key := fmt.Sprintf("%s-%o", sum, info.Mode().Perm())
+		stored := storePath(key[:2], key)
+
+		storedInfo, err := os.Stat(stored)
+		if os.IsNotExist(err) {
+			if err = os.MkdirAll(path.Dir(stored), os.ModePerm); err != nil {
+				return err
+			}
+			return os.Link(p, stored)
+		} else if err != nil {
+			return err
+		}
+
+		if os.SameFile(info, storedInfo) {
+			return nil
+		}
+
+		tmp := p + ".dedupe"
+		if err = os.Link(stored, tmp); err != nil {
+			return err
+		}
+		if err = os.Rename(tmp, p); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		saved += info.Size()
+
+		return nil
+	})
+
+	return saved, err
+}
+
+// Removes the files in the store no extracted toolchain links to anymore.
+func pruneStore() error {
+	return filepath.WalkDir(storePath(), func(p string, d fs.DirEntry, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if count, ok := linkCount(info); ok && count <= 1 {
+			return os.Remove(p)
+		}
+
+		return nil
+	})
+}
+
+// Deduplicates all extracted toolchains.
+func (app *AppState) commandDedupe() {
+	entries, err := os.ReadDir(localDirPath("versions"))
+	if err != nil {
+		app.fail(err)
+	}
+
+	total := int64(0)
+	for _, e := range entries {
+		if !e.IsDir() || isPartialArtifact(e.Name()) {
+			continue
+		}
+
+		logger.infof("Deduplicating %s...", e.Name())
+		saved, err := dedupeDir(localDirPath("versions", e.Name()))
+		if err != nil {
+			app.fail(err)
+		}
+		total += saved
+		logger.infof("Done! (%s saved)\n", humanSize(saved))
+	}
+
+	if err = pruneStore(); err != nil {
+		app.fail(err)
+	}
+
+	logger.infof("Saved %s in total.\n", humanSize(total))
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package main
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// Returns the number of hardlinks to the file described by info.
+func linkCount(info fs.FileInfo) (uint64, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+
+	return uint64(stat.Nlink), true
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package main
+
+import "io/fs"
+
+// Link counts aren't available from a FileInfo on Windows, so store files
+// are never pruned there.
+func linkCount(info fs.FileInfo) (uint64, bool) {
+	return 0, false
+}
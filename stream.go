@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// Extraction of a tarball running concurrently with its download, fed with
+// the downloaded bytes through a pipe.
+type streamExtraction struct {
+	writer *io.PipeWriter
+	tmp    string
+	done   chan error
+}
+
+// Reports whether the download of item should be extracted while
+// downloading. Zip archives can't be extracted before they are complete.
+func (app *AppState) shouldStreamExtract(item *Item) bool {
+	return app.StreamExtract && archiveExt(item.LocalPath) == ".tar.xz" && !isExtracted(extractedDirForItem(item))
+}
+
+func startStreamExtraction() (*streamExtraction, error) {
+	tmp, err := os.MkdirTemp(localDirPath("versions"), ".extract")
+	if err != nil {
+		return nil, err
+	}
+
+	reader, writer := io.Pipe()
+	s := &streamExtraction{writer: writer, tmp: tmp, done: make(chan error, 1)}
+	go func() {
+		err := extractTarXzStream(reader, tmp)
+		if err == nil {
+			// Consume the padding after the end of the archive.
+			_, err = io.Copy(io.Discard, reader)
+		}
+		// Makes the download fail too if the extraction failed.
+		reader.CloseWithError(err)
+		s.done <- err
+	}()
+
+	return s, nil
+}
+
+// Ends the stream, with downloadErr if the download failed, and waits for
+// the extraction to finish.
+func (s *streamExtraction) wait(downloadErr error) error {
+	s.writer.CloseWithError(downloadErr)
+	return <-s.done
+}
+
+// Moves the extracted toolchain into place once the download was verified.
+func (app *AppState) finishStreamExtraction(s *streamExtraction, item *Item) error {
+	defer os.RemoveAll(s.tmp)
+
+	if err := app.installExtracted(item, s.tmp); err != nil {
+		return err
+	}
+
+	app.emitProgress(ProgressEvent{Event: "extract_finished", Version: item.Version.FullString(), Path: extractedDirForItem(item)})
+	return nil
+}
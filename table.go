@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+var itemTableHeaders = []string{"VERSION", "CHANNEL", "DATE", "SIZE", "INSTALLED", "STATUS"}
+
+// Prints items as an aligned table with their channel, release date,
+// download and installed sizes and status, followed by their aliases and
+// where they come from. The marker and status of active and downloaded
+// versions keep their colors.
+func (app *AppState) printItemTable(items []*Item, showPlatforms bool) {
+	green := color.New(color.FgGreen).SprintFunc()
+	blue := color.New(color.FgBlue).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+
+	rows := [][]string{}
+	for _, item := range items {
+		rows = append(rows, itemTableRow(item))
+	}
+
+	widths := make([]int, len(itemTableHeaders))
+	for _, row := range append([][]string{itemTableHeaders}, rows...) {
+		for i, cell := range row {
+			if len(cell) > widths[i] {
+				widths[i] = len(cell)
+			}
+		}
+	}
+
+	fmt.Printf("    %s\n", strings.TrimRight(padCells(itemTableHeaders, widths, nil), " "))
+	for i, item := range items {
+		colorize := fmt.Sprint
+		if item.Current {
+			colorize = green
+		} else if item.Downloaded {
+			colorize = blue
+		}
+
+		line := padCells(rows[i], widths, func(column int, cell string) string {
+			switch {
+			case column == 0 || column == len(widths)-1:
+				return colorize(cell)
+			case column == 1 && item.Master:
+				return red(cell)
+			}
+			return cell
+		})
+
+		notes := app.itemNotes(item)
+		if notes == "" {
+			line = strings.TrimRight(line, " ")
+		}
+		fmt.Printf("%s %s%s\n", colorize("==>"), line, notes)
+
+		if showPlatforms {
+			fmt.Printf("      %s\n", strings.Join(item.Platforms, " "))
+		}
+	}
+}
+
+// Pads every cell to the width of its column, applying style to the padded
+// cell if given, so that colors don't throw off the alignment.
+func padCells(cells []string, widths []int, style func(int, string) string) string {
+	result := []string{}
+	for i, cell := range cells {
+		cell = fmt.Sprintf("%-*s", widths[i], cell)
+		if style != nil {
+			cell = style(i, cell)
+		}
+		result = append(result, cell)
+	}
+
+	return strings.Join(result, "  ")
+}
+
+func itemTableRow(item *Item) []string {
+	date, size, installed := "-", "-", "-"
+	if item.Date != "" {
+		date = item.Date
+	}
+	if item.Size > 0 {
+		size = humanSize(item.Size)
+	}
+
+	dir := ""
+	if item.Custom {
+		dir = item.LocalPath
+	} else if item.Downloaded {
+		dir = extractedDirForItem(item)
+	}
+	if dir != "" && (item.Custom || isExtracted(dir)) {
+		installed = humanSize(dirSize(dir))
+	}
+
+	return []string{item.Name(), item.channel(), date, size, installed, item.status()}
+}
+
+// Returns whether item is active, downloaded or only available remotely.
+func (item *Item) status() string {
+	switch {
+	case item.Current:
+		return "active"
+	case item.Downloaded || item.Custom:
+		return "downloaded"
+	}
+
+	return "remote"
+}
+
+// Describes what the table has no column for: the version and path of
+// custom toolchains, the source, the Mach nominations and the aliases.
+func (app *AppState) itemNotes(item *Item) string {
+	notes := ""
+	if item.Custom {
+		notes += fmt.Sprintf(" [%s, %s]", item.Version.String(), item.LocalPath)
+	}
+	if item.SourceLabel != "" {
+		notes += fmt.Sprintf(" (%s)", item.SourceLabel)
+	}
+	if len(item.Nominations) > 0 {
+		notes += fmt.Sprintf(" [%s]", strings.Join(item.Nominations, ", "))
+	}
+
+	return notes + app.aliasDescription(item)
+}
+
+// Returns the total size of the files under dir.
+func dirSize(dir string) int64 {
+	size := int64(0)
+	filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+
+	return size
+}
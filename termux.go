@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"runtime"
+	"strings"
+)
+
+const (
+	TermuxDefaultPrefix = "/data/data/com.termux/files/usr"
+)
+
+// Reports whether we are running under Termux on Android. Binaries built for
+// linux report GOOS=linux there, so the Termux environment is checked too.
+func isTermux() bool {
+	if runtime.GOOS == "android" {
+		return true
+	}
+
+	return os.Getenv("TERMUX_VERSION") != "" || strings.Contains(os.Getenv("PREFIX"), "com.termux")
+}
+
+func termuxPrefix() string {
+	if prefix := os.Getenv("PREFIX"); prefix != "" {
+		return prefix
+	}
+
+	return TermuxDefaultPrefix
+}
+
+// Official zig builds for Android are the static linux ones, which only
+// exist for these of the architectures Android runs on.
+func isTermuxArchSupported() bool {
+	switch runtime.GOARCH {
+	case "arm64", "amd64", "arm", "386":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Registry of custom toolchains, e.g. self-built compilers, registered with
+// `zig-toolchain link` under a name.
+type ToolchainRegistry struct {
+	// Maps a toolchain name to the directory holding its zig binary.
+	Toolchains map[string]string `json:"toolchains"`
+	// The versions of the toolchains, so that zig isn't asked for its
+	// version on every invocation.
+	Versions map[string]ToolchainVersion `json:"versions,omitempty"`
+}
+
+// Version of a custom toolchain, valid as long as its zig binary has the
+// same modification time and size, i.e. wasn't rebuilt.
+type ToolchainVersion struct {
+	Version string    `json:"version"`
+	ModTime time.Time `json:"mod_time"`
+	Size    int64     `json:"size"`
+}
+
+func toolchainRegistryPath() string {
+	return localDirPath("toolchains.json")
+}
+
+// File in current/ holding the name of the active custom toolchain, since
+// custom toolchains aren't extracted there.
+func currentCustomToolchainPath() string {
+	return localDirPath("current", "custom-toolchain")
+}
+
+func LoadToolchainRegistry() (*ToolchainRegistry, error) {
+	registry := &ToolchainRegistry{Toolchains: map[string]string{}, Versions: map[string]ToolchainVersion{}}
+
+	data, err := os.ReadFile(toolchainRegistryPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return registry, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, registry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", toolchainRegistryPath(), err)
+	}
+	if registry.Versions == nil {
+		registry.Versions = map[string]ToolchainVersion{}
+	}
+
+	return registry, nil
+}
+
+func (r *ToolchainRegistry) Save() error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(toolchainRegistryPath(), data, 0644)
+}
+
+// Returns the directory holding the zig binary of a toolchain at p, which
+// is either an extracted tarball (zig at the root) or an install prefix like
+// zig's stage3 (zig in bin/).
+func findToolchainBinDir(p string) (string, bool) {
+	for _, dir := range []string{p, path.Join(p, "bin")} {
+		info, err := os.Stat(path.Join(dir, zigExeName()))
+		if err == nil && !info.IsDir() {
+			return dir, true
+		}
+	}
+
+	return "", false
+}
+
+// Asks the zig binary in dir for its version.
+func queryZigVersion(dir string) (*Version, error) {
+	out, err := exec.Command(path.Join(dir, zigExeName()), "version").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseVersion(strings.TrimSpace(string(out)))
+}
+
+// Returns the version of the toolchain name at dir, from the registry if its
+// zig binary didn't change since, or else by asking zig. Reports whether the
+// registry was updated.
+func (r *ToolchainRegistry) version(name string, dir string) (*Version, bool, error) {
+	info, err := os.Stat(path.Join(dir, zigExeName()))
+	if err != nil {
+		return nil, false, err
+	}
+
+	if cached, ok := r.Versions[name]; ok && cached.ModTime.Equal(info.ModTime()) && cached.Size == info.Size() {
+		if version, err := ParseVersion(cached.Version); err == nil {
+			return version, false, nil
+		}
+	}
+
+	version, err := queryZigVersion(dir)
+	if err != nil {
+		return nil, false, err
+	}
+	r.Versions[name] = ToolchainVersion{Version: version.FullString(), ModTime: info.ModTime(), Size: info.Size()}
+
+	return version, true, nil
+}
+
+// Adds the registered custom toolchains to the items. The registry is only
+// read once per invocation, and the versions found are only saved with the
+// lock held exclusively.
+func (app *AppState) loadCustomToolchains() error {
+	if app.registry == nil {
+		registry, err := LoadToolchainRegistry()
+		if err != nil {
+			return err
+		}
+		app.registry = registry
+	}
+
+	current, _ := os.ReadFile(currentCustomToolchainPath())
+
+	changed := false
+	for name, dir := range app.registry.Toolchains {
+		item := Item{}
+		item.Custom = true
+		item.CustomName = name
+		item.LocalPath = dir
+		item.Current = strings.TrimSpace(string(current)) == name
+		if version, updated, err := app.registry.version(name, dir); err == nil {
+			item.Version = *version
+			changed = changed || updated
+		}
+		app.Items = append(app.Items, item)
+	}
+
+	if changed && app.holdsExclusiveLock() {
+		if err := app.registry.Save(); err != nil {
+			logger.debugf("Failed to save the toolchain versions: %s\n", err)
+		}
+	}
+
+	return nil
+}
+
+func (app *AppState) GetCustomToolchain(name string) (*Item, bool) {
+	for i := 0; i < len(app.Items); i++ {
+		if app.Items[i].Custom && app.Items[i].CustomName == name {
+			return &app.Items[i], true
+		}
+	}
+
+	return nil, false
+}
+
+func (app *AppState) commandLink(name string, p string) {
+	if isReservedName(name) {
+		logger.errorf("Invalid toolchain name!\n")
+		os.Exit(1)
+	}
+	if _, err := ParseVersion(name); err == nil {
+		logger.errorf("Toolchain names can't be version numbers!\n")
+		os.Exit(1)
+	}
+
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		app.fail(err)
+	}
+
+	dir, ok := findToolchainBinDir(abs)
+	if !ok {
+		logger.errorf("No zig binary found in %s or %s!\n", abs, path.Join(abs, "bin"))
+		os.Exit(1)
+	}
+
+	registry, err := LoadToolchainRegistry()
+	if err != nil {
+		app.fail(err)
+	}
+	registry.Toolchains[name] = dir
+	delete(registry.Versions, name)
+	registry.version(name, dir)
+	if err = registry.Save(); err != nil {
+		app.fail(err)
+	}
+
+	logger.infof("Linked toolchain %s to %s\n", name, dir)
+}
+
+func (app *AppState) commandUnlink(name string) {
+	item, ok := app.GetCustomToolchain(name)
+	if !ok {
+		logger.errorf("Toolchain not found!\n")
+		os.Exit(1)
+	}
+
+	if item.Current {
+		logger.errorf("Can't remove the active toolchain!\n")
+		os.Exit(1)
+	}
+
+	registry, err := LoadToolchainRegistry()
+	if err != nil {
+		app.fail(err)
+	}
+	delete(registry.Toolchains, name)
+	delete(registry.Versions, name)
+	if err = registry.Save(); err != nil {
+		app.fail(err)
+	}
+
+	logger.infof("Removed toolchain %s\n", name)
+}
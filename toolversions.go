@@ -0,0 +1,146 @@
+package main
+
+import (
+	"os"
+	"path"
+	"strings"
+)
+
+// Files of asdf and mise that pin versions of several tools, read for their
+// zig entry when a project has no .zig-version.
+const (
+	ToolVersionsFile = ".tool-versions"
+)
+
+var miseConfigFiles = []string{"mise.toml", ".mise.toml"}
+
+// Files the pin command can write.
+const (
+	PinFormatZigVersion   = "zig-version"
+	PinFormatToolVersions = "tool-versions"
+)
+
+func isValidPinFormat(format string) bool {
+	return format == PinFormatZigVersion || format == PinFormatToolVersions
+}
+
+// Returns the zig version in the contents of a .tool-versions file, e.g.
+// `zig 0.12.0`. Of several versions on the line the first is used, as asdf
+// does, and `system` means zig isn't managed.
+func parseToolVersionsPin(data string) (string, bool) {
+	for _, line := range strings.Split(data, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "zig" {
+			continue
+		}
+
+		return fields[1], fields[1] != "system"
+	}
+
+	return "", false
+}
+
+// Returns the zig version in the [tools] table of a mise.toml, given as
+// `zig = "0.12.0"`, `zig = ["0.12.0", ...]` or `zig = { version = "0.12.0" }`.
+// Only as much TOML as those take is understood.
+func parseMisePin(data string) (string, bool) {
+	inTools := false
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			inTools = line == "[tools]"
+			continue
+		}
+		if !inTools {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.Trim(strings.TrimSpace(key), `"'`) != "zig" {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, "{") {
+			_, value, ok = strings.Cut(value, "version")
+			if !ok {
+				return "", false
+			}
+			value = strings.TrimLeft(strings.TrimSpace(value), "=")
+		}
+
+		return firstTomlString(value)
+	}
+
+	return "", false
+}
+
+// Returns the first quoted string in value.
+func firstTomlString(value string) (string, bool) {
+	start := strings.IndexAny(value, `"'`)
+	if start < 0 {
+		return "", false
+	}
+
+	quote := value[start]
+	end := strings.IndexByte(value[start+1:], quote)
+	if end < 0 {
+		return "", false
+	}
+
+	pin := value[start+1 : start+1+end]
+	return pin, pin != ""
+}
+
+// Reads the zig version pinned by the asdf or mise files in dir, and returns
+// it with the file it comes from.
+func readToolPin(dir string) (string, string, bool) {
+	file := path.Join(dir, ToolVersionsFile)
+	if data, err := os.ReadFile(file); err == nil {
+		if pin, ok := parseToolVersionsPin(string(data)); ok {
+			return pin, file, true
+		}
+	}
+
+	for _, name := range miseConfigFiles {
+		file = path.Join(dir, name)
+		if data, err := os.ReadFile(file); err == nil {
+			if pin, ok := parseMisePin(string(data)); ok {
+				return pin, file, true
+			}
+		}
+	}
+
+	return "", "", false
+}
+
+// Sets the zig entry of the .tool-versions in dir to pin, keeping the other
+// tools, and returns the file's path.
+func writeToolVersionsPin(dir string, pin string) (string, error) {
+	file := path.Join(dir, ToolVersionsFile)
+
+	lines := []string{}
+	if data, err := os.ReadFile(file); err == nil && strings.TrimSpace(string(data)) != "" {
+		lines = strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	} else if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	replaced := false
+	for i, line := range lines {
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] == "zig" {
+			lines[i] = "zig " + pin
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		lines = append(lines, "zig "+pin)
+	}
+
+	return file, os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// Returns the current master build from the index.
+func (app *AppState) masterItem() (*Item, bool) {
+	for i := 0; i < len(app.Items); i++ {
+		if app.Items[i].Master {
+			return &app.Items[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// Returns the newest tagged release from the index.
+func (app *AppState) latestStableItem() (*Item, bool) {
+	var latest *Item
+	for i := 0; i < len(app.Items); i++ {
+		item := &app.Items[i]
+		if !item.Indexed || item.Custom || item.Version.isPrerelease() {
+			continue
+		}
+		if latest == nil || item.Version.moreThan(latest.Version) {
+			latest = item
+		}
+	}
+
+	return latest, latest != nil
+}
+
+// Activates the newest version of the given channel if it is newer than the
+// active version. With removeOld the previously active version is
+// removed afterwards, unless a known project pins it.
+func (app *AppState) commandUpgrade(channel string, removeOld bool) {
+	if !isChannel(channel) {
+		logger.errorf("Invalid channel! Expected stable, master or mach.\n")
+		os.Exit(1)
+	}
+
+	target, ok := app.channelItem(channel)
+	if !ok {
+		logger.errorf("No %s version found in the index!\n", channel)
+		os.Exit(1)
+	}
+
+	// Mach nominations may go back to an older build than the active one,
+	// which is still what tracking the channel means.
+	previous, hasPrevious := app.GetCurrentActiveItem()
+	if hasPrevious && !previous.Custom {
+		upToDate := !target.Version.moreThan(previous.Version)
+		if channel == ChannelMach {
+			upToDate = target.Version.equal(previous.Version)
+		}
+		if upToDate {
+			logger.infof("zig %s is up to date.\n", previous.Version.String())
+			return
+		}
+	}
+
+	if hasPrevious {
+		logger.infof("Upgrading from zig %s to %s\n", previous.Name(), target.Version.String())
+	}
+	app.commandActivateItem(target)
+
+	if !removeOld || !hasPrevious || previous.Custom || !previous.Downloaded {
+		return
+	}
+
+	registry, err := LoadProjectRegistry()
+	if err != nil {
+		app.fail(err)
+	}
+	if projects := app.projectsReferencing(registry, previous); len(projects) > 0 {
+		logger.infof("Keeping %s (pinned by %s)\n", previous.Version.String(), strings.Join(projects, ", "))
+		return
+	}
+
+	previous.Current = false
+	if app.DryRun {
+		app.printRemovalPlan(previous)
+		return
+	}
+
+	logger.infof("Removing %s...", previous.Version.String())
+	if err = app.removeItem(previous); err != nil {
+		app.fail(err)
+	}
+	logger.infof("Done!\n")
+
+	if err = pruneStore(); err != nil {
+		app.fail(err)
+	}
+}
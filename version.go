@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A zig version, following semver: MAJOR.MINOR.PATCH, optionally followed by
+// prerelease identifiers (`-dev.1234`, `-rc1`) and build metadata, which
+// zig uses for the commit of dev builds (`+a3f634`).
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+
+	// Prerelease identifiers, e.g. "dev.1234" or "rc1". Empty for releases.
+	Prerelease string
+
+	// Set for dev builds, whose Build is the number of commits since the
+	// previous release.
+	Dev   bool
+	Build int
+
+	// Build metadata, the commit of dev builds. It is ignored when
+	// comparing versions.
+	Commit string
+}
+
+// Short form of the version, e.g. 0.11.0 or 0.11.0-dev-1234.
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + strings.Replace(v.Prerelease, "dev.", "dev-", 1)
+	}
+	return s
+}
+
+// Returns the version in the form used by zig itself, e.g.
+// 0.11.0-dev.1234+a3f634.
+func (v Version) FullString() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Commit != "" {
+		s += "+" + v.Commit
+	}
+	return s
+}
+
+// Reports whether v is a prerelease (a dev build or a release candidate).
+func (v Version) isPrerelease() bool {
+	return v.Prerelease != ""
+}
+
+// Compares v and other by semver precedence, returning -1, 0 or 1.
+func (v Version) compare(other Version) int {
+	if c := compareInts(v.Major, other.Major); c != 0 {
+		return c
+	}
+	if c := compareInts(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+	if c := compareInts(v.Patch, other.Patch); c != 0 {
+		return c
+	}
+
+	// A prerelease comes before the release itself.
+	if v.Prerelease == "" || other.Prerelease == "" {
+		if v.Prerelease == other.Prerelease {
+			return 0
+		} else if v.Prerelease == "" {
+			return 1
+		}
+		return -1
+	}
+
+	a := strings.Split(v.Prerelease, ".")
+	b := strings.Split(other.Prerelease, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := compareIdentifiers(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+
+	return compareInts(len(a), len(b))
+}
+
+func (v Version) equal(other Version) bool {
+	return v.compare(other) == 0
+}
+
+func (v Version) lessThan(other Version) bool {
+	return v.compare(other) < 0
+}
+
+func (v Version) moreThan(other Version) bool {
+	return v.compare(other) > 0
+}
+
+func compareInts(a int, b int) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+// Compares two prerelease identifiers. Numeric identifiers compare
+// numerically and before alphanumeric ones, as in semver. Alphanumeric ones
+// compare their runs of digits numerically, so that rc10 comes after rc9.
+func compareIdentifiers(a string, b string) int {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	if errA == nil && errB == nil {
+		return compareInts(na, nb)
+	} else if errA == nil {
+		return -1
+	} else if errB == nil {
+		return 1
+	}
+
+	for a != "" && b != "" {
+		ra, restA := splitRun(a)
+		rb, restB := splitRun(b)
+
+		na, errA := strconv.Atoi(ra)
+		nb, errB := strconv.Atoi(rb)
+		if errA == nil && errB == nil {
+			if c := compareInts(na, nb); c != 0 {
+				return c
+			}
+		} else if c := strings.Compare(ra, rb); c != 0 {
+			return c
+		}
+
+		a, b = restA, restB
+	}
+
+	return compareInts(len(a), len(b))
+}
+
+// Splits the leading run of digits or of non-digits off s.
+func splitRun(s string) (string, string) {
+	isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+
+	i := 1
+	for i < len(s) && isDigit(s[i]) == isDigit(s[0]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func isValidIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// Given a version string in the form 0.10.1, 0.12.0-rc1 or
+// 0.11.0-dev.1234+a3f634, return the corresponding Version object. The
+// 0.11.0-dev-1234 form printed by older releases is accepted too.
+func ParseVersion(v string) (*Version, error) {
+	result := &Version{}
+	s := strings.TrimPrefix(strings.TrimSpace(v), "v")
+
+	if i := strings.Index(s, "+"); i >= 0 {
+		result.Commit = s[i+1:]
+		s = s[:i]
+		for _, id := range strings.Split(result.Commit, ".") {
+			if !isValidIdentifier(id) {
+				return nil, fmt.Errorf("Failed to parse version: %s", v)
+			}
+		}
+	}
+
+	if i := strings.Index(s, "-"); i >= 0 {
+		result.Prerelease = s[i+1:]
+		s = s[:i]
+		if strings.HasPrefix(result.Prerelease, "dev-") {
+			result.Prerelease = "dev." + strings.TrimPrefix(result.Prerelease, "dev-")
+		}
+		for _, id := range strings.Split(result.Prerelease, ".") {
+			if !isValidIdentifier(id) {
+				return nil, fmt.Errorf("Failed to parse version: %s", v)
+			}
+		}
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("Failed to parse version: %s", v)
+	}
+
+	numbers := [3]int{}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse version: %s", v)
+		}
+		numbers[i] = n
+	}
+	result.Major, result.Minor, result.Patch = numbers[0], numbers[1], numbers[2]
+
+	// Dev builds are numbered, e.g. dev.1234.
+	ids := strings.Split(result.Prerelease, ".")
+	if ids[0] == "dev" {
+		result.Dev = true
+		if len(ids) > 1 {
+			if build, err := strconv.Atoi(ids[1]); err == nil {
+				result.Build = build
+			}
+		}
+	}
+
+	return result, nil
+}
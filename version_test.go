@@ -0,0 +1,92 @@
+package main
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Version
+	}{
+		{"0.11.0", Version{Major: 0, Minor: 11, Patch: 0}},
+		{"v1.2.3", Version{Major: 1, Minor: 2, Patch: 3}},
+		{"0.12.0-rc1", Version{Minor: 12, Prerelease: "rc1"}},
+		{"0.12.0-dev.1234+a3f634", Version{Minor: 12, Prerelease: "dev.1234", Dev: true, Build: 1234, Commit: "a3f634"}},
+		{"0.11.0-dev-1234", Version{Minor: 11, Prerelease: "dev.1234", Dev: true, Build: 1234}},
+	}
+
+	for _, test := range tests {
+		got, err := ParseVersion(test.input)
+		if err != nil {
+			t.Errorf("ParseVersion(%q) failed: %s", test.input, err)
+			continue
+		}
+		if *got != test.want {
+			t.Errorf("ParseVersion(%q) = %+v, want %+v", test.input, *got, test.want)
+		}
+	}
+}
+
+func TestParseVersionInvalid(t *testing.T) {
+	for _, input := range []string{"", "master", "0.11", "0.11.0.1", "0.x.0", "0.11.0-", "0.11.0-rc..1", "0.11.0+", "0.11.0-rc_1"} {
+		if v, err := ParseVersion(input); err == nil {
+			t.Errorf("ParseVersion(%q) = %+v, want an error", input, *v)
+		}
+	}
+}
+
+func TestVersionString(t *testing.T) {
+	v, err := ParseVersion("0.12.0-dev.1234+a3f634")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := v.String(); s != "0.12.0-dev-1234" {
+		t.Errorf("String() = %q, want 0.12.0-dev-1234", s)
+	}
+	if s := v.FullString(); s != "0.12.0-dev.1234+a3f634" {
+		t.Errorf("FullString() = %q, want 0.12.0-dev.1234+a3f634", s)
+	}
+}
+
+func TestVersionLessThan(t *testing.T) {
+	// Each version comes before the next one.
+	ordered := []string{
+		"0.9.1",
+		"0.10.0",
+		"0.10.1",
+		"0.11.0-dev.9",
+		"0.11.0-dev.10",
+		"0.11.0-dev.1234",
+		"0.11.0-rc1",
+		"0.11.0-rc2",
+		"0.11.0-rc10",
+		"0.11.0",
+		"0.12.0-dev.1",
+		"0.12.0",
+		"1.0.0",
+	}
+
+	versions := make([]*Version, len(ordered))
+	for i, s := range ordered {
+		v, err := ParseVersion(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions[i] = v
+	}
+
+	for i, a := range versions {
+		for j, b := range versions {
+			if got, want := a.lessThan(*b), i < j; got != want {
+				t.Errorf("%s.lessThan(%s) = %v, want %v", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+}
+
+func TestVersionIgnoresCommit(t *testing.T) {
+	a, _ := ParseVersion("0.12.0-dev.1234+a3f634")
+	b, _ := ParseVersion("0.12.0-dev.1234+b5e7c1")
+	if !a.equal(*b) || a.lessThan(*b) || b.lessThan(*a) {
+		t.Errorf("versions differing only in their commit should be equal")
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// File in current/ holding the name of the active version's directory in
+// versions/.
+func currentVersionPath() string {
+	return localDirPath("current", "version")
+}
+
+// Returns the directory name of the active version, which is also its
+// tarball's base name. Older releases of this tool extracted the active
+// version into current/ itself, which is still recognized.
+func currentVersionName() (string, bool) {
+	if data, err := os.ReadFile(currentVersionPath()); err == nil {
+		return strings.TrimSpace(string(data)), true
+	}
+
+	dir, err := os.ReadDir(localDirPath("current"))
+	if err != nil {
+		return "", false
+	}
+
+	for _, e := range dir {
+		if strings.HasPrefix(e.Name(), "zig") && e.IsDir() {
+			return e.Name(), true
+		}
+	}
+
+	return "", false
+}
+
+// Stable path of the active toolchain directory, for tools that need its lib/
+// or doc/ directories.
+func activeDirPath() string {
+	return localDirPath("active")
+}
+
+// Points the active symlink at dir, replacing it atomically. Directory
+// symlinks need elevated privileges on Windows, so it isn't maintained there.
+func linkActiveDir(dir string) error {
+	if getHostOs() == "windows" {
+		return nil
+	}
+
+	tmp := activeDirPath() + ".tmp"
+	os.Remove(tmp)
+	if err := os.Symlink(dir, tmp); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, activeDirPath())
+}
+
+// Reports whether dir holds an extracted toolchain.
+func isExtracted(dir string) bool {
+	_, err := os.Stat(path.Join(dir, zigExeName()))
+	return err == nil
+}
+
+// Extracts the tarball of item into its directory in versions/. The tarball
+// is extracted to a temporary directory first, so an interrupted extraction
+// never leaves a partial toolchain behind.
+func (app *AppState) extractItem(item *Item, onProgress extractProgressFunc) error {
+	tmp, err := os.MkdirTemp(localDirPath("versions"), ".extract")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	if err = extractTarballWithProgress(item.LocalPath, tmp, onProgress); err != nil {
+		return err
+	}
+
+	return app.installExtracted(item, tmp)
+}
+
+// Moves the toolchain of item extracted into tmp into its directory in
+// versions/, after validating and fixing it up.
+func (app *AppState) installExtracted(item *Item, tmp string) error {
+	dir := extractedDirForItem(item)
+	extracted := path.Join(tmp, path.Base(dir))
+	if !isExtracted(extracted) {
+		return fmt.Errorf("%s: no zig binary found in %s/", item.LocalPath, path.Base(dir))
+	}
+
+	if err := fixToolchainPermissions(extracted); err != nil {
+		return err
+	}
+
+	if err := writeManifest(item, extracted); err != nil {
+		return err
+	}
+
+	if !app.Config.KeepQuarantine {
+		if err := stripQuarantine(extracted); err != nil {
+			return err
+		}
+	}
+
+	if app.Config.Dedupe {
+		if _, err := dedupeDir(extracted); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(extracted, dir); err != nil {
+		return err
+	}
+
+	app.recordExtracted(item)
+	return nil
+}
+
+// Returns the directory the zig symlink should point into for the toolchain
+// at dir: the stable active path when configured, so that the zig symlink
+// itself never changes.
+func (app *AppState) linkSource(dir string) string {
+	if app.Config.LinkViaActive && app.LinkMode == LinkModeSymlink && getHostOs() != "windows" {
+		return activeDirPath()
+	}
+
+	return dir
+}
+
+// Returns the directory of the active toolchain, the marker file in current/
+// recording it and the marker's contents.
+func (app *AppState) activeToolchain() (string, string, string, bool) {
+	item, ok := app.GetCurrentActiveItem()
+	if !ok {
+		return "", "", "", false
+	}
+
+	if item.Custom {
+		return item.LocalPath, currentCustomToolchainPath(), item.CustomName, true
+	}
+
+	name, _ := currentVersionName()
+	if dir := localDirPath("current", name); isExtracted(dir) {
+		return dir, currentVersionPath(), name, true
+	}
+
+	return localDirPath("versions", name), currentVersionPath(), name, true
+}
+
+// Writes name to the marker file at markerPath, replacing any other marker.
+func writeCurrentMarker(markerPath string, name string) error {
+	for _, p := range []string{currentVersionPath(), currentCustomToolchainPath()} {
+		if p != markerPath {
+			os.Remove(p)
+		}
+	}
+
+	tmp := markerPath + ".tmp"
+	if err := os.WriteFile(tmp, []byte(name), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, markerPath)
+}
+
+// Makes the toolchain at dir the active one, recording name in the marker
+// file at markerPath. On failure the previously active toolchain is
+// restored, so there is always a working zig.
+func (app *AppState) switchToolchain(dir string, markerPath string, name string) error {
+	previousDir, previousMarker, previousName, hadPrevious := app.activeToolchain()
+	previousMode := loadLinkRecord().Mode
+	if previousMode == "" {
+		previousMode = app.LinkMode
+	}
+
+	// The active symlink is only required when zig is linked through it, so
+	// that copying still works where symlinks can't be created at all.
+	err := linkActiveDir(dir)
+	if err != nil && app.linkSource(dir) != activeDirPath() {
+		os.Remove(activeDirPath())
+		err = nil
+	}
+	if err == nil {
+		err = linkToolchain(app.linkSource(dir), app.LinkMode, app.Force)
+	}
+	if err == nil {
+		err = writeCurrentMarker(markerPath, name)
+	}
+
+	if err != nil {
+		if hadPrevious {
+			linkActiveDir(previousDir)
+			if restoreErr := linkToolchain(app.linkSource(previousDir), previousMode, false); restoreErr != nil {
+				return fmt.Errorf("%w (restoring the previous version also failed: %s)", err, restoreErr)
+			}
+			writeCurrentMarker(previousMarker, previousName)
+		} else {
+			unlinkToolchain()
+			os.Remove(activeDirPath())
+		}
+		return err
+	}
+
+	if markerPath == currentVersionPath() {
+		app.recordActive(name)
+	} else {
+		app.recordActive("")
+	}
+
+	return nil
+}
+
+// Returns the names of the toolchains extracted into current/ by older
+// releases.
+func legacyCurrentDirs() []string {
+	dirs := []string{}
+	entries, err := os.ReadDir(localDirPath("current"))
+	if err != nil {
+		return dirs
+	}
+
+	for _, e := range entries {
+		if e.IsDir() {
+			dirs = append(dirs, e.Name())
+		}
+	}
+
+	return dirs
+}
+
+// Toolchains extracted into current/ by older releases are no longer needed
+// once another one is active, but are only deleted once confirmed.
+func (app *AppState) removeLegacyCurrentDirs() {
+	dirs := legacyCurrentDirs()
+	if len(dirs) == 0 {
+		return
+	}
+
+	question := fmt.Sprintf("Delete the toolchains left in %s by an older release (%s)?", localDirPath("current"), strings.Join(dirs, ", "))
+	if !app.confirmDestructive(question) {
+		logger.infof("Keeping %s\n", strings.Join(dirs, ", "))
+		return
+	}
+
+	for _, dir := range dirs {
+		if err := os.RemoveAll(localDirPath("current", dir)); err != nil {
+			logger.warnf("Failed to delete %s: %s\n", dir, err)
+		}
+	}
+}
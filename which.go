@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Prints the absolute path of the active zig binary, or with lib of its lib
+// directory, for editor and zls configurations. Like current, it only reads
+// the local state.
+func (app *AppState) commandWhich(lib bool) {
+	item, ok := app.activeLocalItem()
+	if !ok {
+		fmt.Fprintf(os.Stderr, "No active version.\n")
+		os.Exit(1)
+	}
+
+	dir := activeItemDir(item)
+	p := path.Join(dir, zigExeName())
+	if lib {
+		p = toolchainLibDir(dir)
+		if _, err := os.Stat(p); err != nil {
+			logger.errorf("Failed to find the lib directory of zig %s!\n", item.Name())
+			os.Exit(1)
+		}
+	}
+
+	// The toolchain may be reached through symlinks, e.g. the stable active
+	// path.
+	if resolved, err := filepath.EvalSymlinks(p); err == nil {
+		p = resolved
+	}
+	if abs, err := filepath.Abs(p); err == nil {
+		p = abs
+	}
+
+	fmt.Printf("%s\n", p)
+}
+
+// Returns the directory holding the zig binary of the active item.
+func activeItemDir(item *Item) string {
+	if item.Custom {
+		return item.LocalPath
+	}
+
+	// Versions extracted into current/ by older releases.
+	if name, ok := currentVersionName(); ok {
+		if dir := localDirPath("current", name); isExtracted(dir) {
+			return dir
+		}
+	}
+
+	return extractedDirForItem(item)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// File name of the zig binary inside a toolchain directory.
+func zigExeName() string {
+	if getHostOs() == "windows" {
+		return "zig.exe"
+	}
+
+	return "zig"
+}
+
+// Directory for per-user program data on Windows, where symlinks into the
+// home directory are of little use. Empty elsewhere, or when unset.
+func localAppDataDir() string {
+	if getHostOs() != "windows" {
+		return ""
+	}
+
+	return filepath.ToSlash(os.Getenv("LOCALAPPDATA"))
+}
+
+// Path of the shim script that runs the active zig, used with the shim link
+// mode: a .cmd batch file on Windows, a shell script elsewhere.
+func zigShimPath() string {
+	if getHostOs() == "windows" {
+		return path.Join(path.Dir(zigBinPath()), "zig.cmd")
+	}
+
+	return zigBinPath()
+}
+
+// Contents of a shim script forwarding all arguments to zig.
+func zigShimScript(zig string) string {
+	if getHostOs() == "windows" {
+		return "@echo off\r\n\"" + filepath.FromSlash(zig) + "\" %*\r\n"
+	}
+
+	return "#!/bin/sh\nexec \"" + zig + "\" \"$@\"\n"
+}
+
+// Contents of a shim script running zig through `zig-toolchain exec`, which
+// picks the version per invocation.
+func zigAutoShimScript(exe string) string {
+	if getHostOs() == "windows" {
+		return "@echo off\r\n\"" + filepath.FromSlash(exe) + "\" exec -- zig %*\r\n"
+	}
+
+	return "#!/bin/sh\nexec \"" + exe + "\" exec -- zig \"$@\"\n"
+}
+
+// Returns the path of the running zig-toolchain executable, for shims to
+// call back into.
+func toolExecutablePath() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+
+	return filepath.ToSlash(exe), nil
+}